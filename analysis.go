@@ -0,0 +1,519 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ClusterThoughtsInput struct {
+	Threshold float64  `json:"threshold,omitempty" jsonschema:"minimum cosine similarity between 0 and 1 for a thought to join a cluster, defaults to 0.3"`
+	Stopwords []string `json:"stopwords,omitempty" jsonschema:"words to ignore when comparing thoughts, replaces the default English stopword list"`
+}
+
+// Cluster is a group of thoughts about a similar topic.
+type Cluster struct {
+	Members        []int `json:"members"`        // 1-based indices of the thoughts in the cluster
+	Representative int   `json:"representative"` // 1-based index of the thought closest to the cluster's center
+}
+
+// ClusterThoughts is a tool that groups the thoughts by rough topic.
+//
+// Thoughts are compared as bags of words by cosine similarity. They are
+// assigned greedily in log order: each thought joins the cluster whose
+// combined bag of words it is most similar to, provided the similarity
+// reaches the threshold, and starts a new cluster otherwise. The result is
+// deterministic for a given log.
+func (t *ThinkTool) ClusterThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ClusterThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	threshold := params.Arguments.Threshold
+	if threshold <= 0 {
+		threshold = 0.3
+	}
+	if threshold > 1 {
+		return nil, toolError(CodeOutOfRange, "invalid threshold %v, expected a value between 0 and 1", threshold)
+	}
+	stopwords := stopwordSet(params.Arguments.Stopwords)
+
+	vectors := make([]map[string]float64, len(t.thoughts))
+	centers := []map[string]float64{}
+	members := [][]int{}
+	for i, thought := range t.thoughts {
+		vectors[i] = termVector(thought.Thought, stopwords)
+
+		best, bestScore := -1, 0.0
+		for c, center := range centers {
+			if score := cosine(vectors[i], center); score > bestScore {
+				best, bestScore = c, score
+			}
+		}
+		if best < 0 || bestScore < threshold {
+			centers = append(centers, map[string]float64{})
+			members = append(members, nil)
+			best = len(centers) - 1
+		}
+		for w, n := range vectors[i] {
+			centers[best][w] += n
+		}
+		members[best] = append(members[best], i)
+	}
+
+	clusters := make([]Cluster, len(members))
+	sections := []string{}
+	for c, group := range members {
+		representative, bestScore := group[0], -1.0
+		indices := []string{}
+		for _, i := range group {
+			if score := cosine(vectors[i], centers[c]); score > bestScore {
+				representative, bestScore = i, score
+			}
+			clusters[c].Members = append(clusters[c].Members, i+1)
+			indices = append(indices, fmt.Sprintf("#%d", i+1))
+		}
+		clusters[c].Representative = representative + 1
+		sections = append(sections, fmt.Sprintf("Cluster %d (%d thought(s): %s)\nRepresentative #%d: %s\n",
+			c+1, len(group), strings.Join(indices, ", "), representative+1, tidyThought(t.thoughts[representative].Thought)))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(sections, "\n")}},
+		StructuredContent: map[string]any{"clusters": clusters},
+	}, nil
+}
+
+// LogComplexity is the structured result of the log_complexity tool.
+type LogComplexity struct {
+	Thoughts        int     `json:"thoughts"`
+	Characters      int     `json:"characters"`
+	Words           int     `json:"words"`
+	ReadingMinutes  float64 `json:"reading_minutes"` // At 200 words per minute
+	EstimatedTokens int     `json:"estimated_tokens"`
+}
+
+// LogComplexity is a tool that estimates how much content the log holds.
+func (t *ThinkTool) LogComplexity(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	c := LogComplexity{Thoughts: len(t.thoughts)}
+	for _, thought := range t.thoughts {
+		c.Characters += utf8.RuneCountInString(thought.Thought)
+		c.Words += len(strings.Fields(thought.Thought))
+		c.EstimatedTokens += estimateTokens(thought.Thought)
+	}
+	c.ReadingMinutes = float64(c.Words) / 200
+
+	text := fmt.Sprintf("%d thought(s), %d characters, %d words, about %.1f minute(s) of reading and %d tokens.",
+		c.Thoughts, c.Characters, c.Words, c.ReadingMinutes, c.EstimatedTokens)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}, StructuredContent: c}, nil
+}
+
+// negations are the words that negate a statement. Contractions such as
+// "isn't" are tokenized into "isn" and "t", so the "t" is counted as well.
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nothing": true, "cannot": true,
+	"neither": true, "nor": true, "without": true, "t": true,
+}
+
+// antonyms are word pairs that usually state opposite facts.
+var antonyms = [][2]string{
+	{"true", "false"}, {"correct", "incorrect"}, {"valid", "invalid"},
+	{"possible", "impossible"}, {"always", "never"}, {"works", "fails"}, {"safe", "unsafe"},
+	{"increase", "decrease"}, {"increases", "decreases"}, {"enabled", "disabled"},
+	{"required", "optional"}, {"success", "failure"}, {"succeeds", "fails"}, {"before", "after"},
+}
+
+// statement splits text into its content words, without negations and
+// stopwords, and whether it is negated, i.e. has an odd number of negations.
+func statement(text string) (words map[string]float64, negated bool) {
+	words = map[string]float64{}
+	for _, w := range tokenize(text) {
+		switch {
+		case negations[w]:
+			negated = !negated
+		case !defaultStopwordSet[w]:
+			words[w]++
+		}
+	}
+	return words, negated
+}
+
+// defaultStopwordSet is the set of the default stopwords.
+var defaultStopwordSet = stopwordSet(nil)
+
+// opposed reports whether a and b contain the two words of an antonym pair
+// that do not both occur in either of them, e.g. "true" in a and "false" in b.
+func opposed(a, b map[string]float64) (string, bool) {
+	for _, pair := range antonyms {
+		x, y := pair[0], pair[1]
+		if a[x] > 0 && b[y] > 0 && a[y] == 0 && b[x] == 0 || a[y] > 0 && b[x] > 0 && a[x] == 0 && b[y] == 0 {
+			return x + "/" + y, true
+		}
+	}
+	return "", false
+}
+
+type FindContradictionsInput struct {
+	Threshold float64 `json:"threshold,omitempty" jsonschema:"minimum similarity between 0 and 1 of the two thoughts of a pair apart from negations, defaults to 0.5"`
+	Limit     int     `json:"limit,omitempty" jsonschema:"maximum number of pairs to return, defaults to 10"`
+}
+
+// Contradiction is a pair of thoughts that may contradict each other.
+type Contradiction struct {
+	A      int     `json:"a"` // 1-based index of the earlier thought
+	B      int     `json:"b"` // 1-based index of the later thought
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// FindContradictions is a tool that returns pairs of thoughts that may
+// contradict each other.
+//
+// It is a heuristic that only looks at words: two thoughts are a candidate
+// pair if their words, ignoring negations and stopwords, are similar by
+// cosine similarity, and one of them is negated while the other is not, or
+// they contain the two words of a known antonym pair. It understands neither
+// grammar nor meaning, so it misses contradictions phrased with different
+// words and flags thoughts that merely discuss both sides, e.g. a question
+// and its answer. Only English negations and antonyms are known.
+func (t *ThinkTool) FindContradictions(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[FindContradictionsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	threshold := params.Arguments.Threshold
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+	if threshold > 1 {
+		return nil, toolError(CodeOutOfRange, "invalid threshold %v, expected a value between 0 and 1", threshold)
+	}
+	limit := params.Arguments.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	words := make([]map[string]float64, len(t.thoughts))
+	negated := make([]bool, len(t.thoughts))
+	for i, thought := range t.thoughts {
+		words[i], negated[i] = statement(thought.Thought)
+	}
+	found := []Contradiction{}
+	for i := range t.thoughts {
+		for j := i + 1; j < len(t.thoughts); j++ {
+			score := cosine(words[i], words[j])
+			if score < threshold {
+				continue
+			}
+			if negated[i] != negated[j] {
+				found = append(found, Contradiction{A: i + 1, B: j + 1, Score: score, Reason: "one of them is negated"})
+			} else if pair, ok := opposed(words[i], words[j]); ok {
+				found = append(found, Contradiction{A: i + 1, B: j + 1, Score: score, Reason: "opposite words " + pair})
+			}
+		}
+	}
+	if len(found) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No contradicting thoughts found."}}}, nil
+	}
+	slices.SortStableFunc(found, func(a, b Contradiction) int { return cmp.Compare(b.Score, a.Score) })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	lines := []string{}
+	for _, c := range found {
+		lines = append(lines, fmt.Sprintf("Thoughts #%d and #%d may contradict each other (similarity %.2f, %s):\n  #%d: %s\n  #%d: %s\n", c.A, c.B, c.Score, c.Reason, c.A, truncate(t.thoughts[c.A-1].Thought, 120), c.B, truncate(t.thoughts[c.B-1].Thought, 120)))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"contradictions": found},
+	}, nil
+}
+
+type VocabularyStatsInput struct {
+	Top       int      `json:"top,omitempty" jsonschema:"number of most frequent words to return, defaults to 10"`
+	Stopwords []string `json:"stopwords,omitempty" jsonschema:"words to ignore, replaces the default English stopword list"`
+}
+
+// WordCount is a word and how often it occurs.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// VocabularyStats is a tool that reports the number of distinct words across
+// all thoughts and the most frequent ones. Words are split as by tokenize:
+// maximal runs of letters and digits, lowercased; stopwords are not counted.
+// Ties are ordered alphabetically.
+func (t *ThinkTool) VocabularyStats(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[VocabularyStatsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	top := params.Arguments.Top
+	if top < 0 {
+		return nil, toolError(CodeOutOfRange, "top must not be negative")
+	}
+	if top == 0 {
+		top = 10
+	}
+	stopwords := stopwordSet(params.Arguments.Stopwords)
+
+	counts := map[string]int{}
+	total := 0
+	for _, thought := range t.thoughts {
+		for _, w := range tokenize(thought.Thought) {
+			if !stopwords[w] {
+				counts[w]++
+				total++
+			}
+		}
+	}
+	words := []WordCount{}
+	for w, n := range counts {
+		words = append(words, WordCount{Word: w, Count: n})
+	}
+	slices.SortFunc(words, func(a, b WordCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	unique := len(words)
+	if len(words) > top {
+		words = words[:top]
+	}
+
+	lines := []string{fmt.Sprintf("%d distinct word(s) in %d word(s), not counting stopwords.", unique, total)}
+	if len(words) > 0 {
+		lines = append(lines, "", "Most frequent words:")
+	}
+	for _, w := range words {
+		lines = append(lines, fmt.Sprintf("  %s: %d", w.Word, w.Count))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"unique_words": unique, "total_words": total, "top": words},
+	}, nil
+}
+
+// untaggedBucket is the name under which tag_stats reports the thoughts
+// without tags.
+const untaggedBucket = "(untagged)"
+
+// TagStat summarizes the thoughts with a tag.
+type TagStat struct {
+	Tag           string  `json:"tag"`
+	Thoughts      int     `json:"thoughts"`
+	TotalLength   int     `json:"total_length"` // In characters
+	AverageLength float64 `json:"average_length"`
+	First         string  `json:"first"` // Creation time of the earliest thought
+	Last          string  `json:"last"`  // Creation time of the latest thought
+	SpanSeconds   float64 `json:"span_seconds"`
+}
+
+// TagStats is a tool that reports, per tag, how many thoughts have it, how
+// long they are and over which period they were recorded. A thought with
+// several tags counts towards each of them, thoughts without tags are grouped
+// under untaggedBucket. Tags are sorted by decreasing thought count.
+func (t *ThinkTool) TagStats(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	type span struct{ first, last time.Time }
+	stats := map[string]*TagStat{}
+	spans := map[string]*span{}
+	for i, thought := range t.thoughts {
+		createdAt, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		tags := thought.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedBucket}
+		}
+		for _, tag := range tags {
+			s, ok := stats[tag]
+			if !ok {
+				s = &TagStat{Tag: tag}
+				stats[tag] = s
+				spans[tag] = &span{createdAt, createdAt}
+			}
+			s.Thoughts++
+			s.TotalLength += utf8.RuneCountInString(thought.Thought)
+			if sp := spans[tag]; createdAt.Before(sp.first) {
+				sp.first = createdAt
+			} else if createdAt.After(sp.last) {
+				sp.last = createdAt
+			}
+		}
+	}
+
+	report := []TagStat{}
+	for tag, s := range stats {
+		sp := spans[tag]
+		s.AverageLength = float64(s.TotalLength) / float64(s.Thoughts)
+		s.First = sp.first.Format(time.RFC3339)
+		s.Last = sp.last.Format(time.RFC3339)
+		s.SpanSeconds = sp.last.Sub(sp.first).Seconds()
+		report = append(report, *s)
+	}
+	slices.SortFunc(report, func(a, b TagStat) int {
+		if c := cmp.Compare(b.Thoughts, a.Thoughts); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+
+	lines := []string{}
+	for _, s := range report {
+		lines = append(lines, fmt.Sprintf("%s: %d thought(s), %d characters (%.1f on average), from %s to %s (%s)",
+			s.Tag, s.Thoughts, s.TotalLength, s.AverageLength, s.First, s.Last, time.Duration(s.SpanSeconds*float64(time.Second))))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"tags": report},
+	}, nil
+}
+
+type RankThoughtsInput struct {
+	Limit int `json:"limit,omitempty" jsonschema:"maximum number of thoughts to return, defaults to 10"`
+}
+
+// RankedThought is a thought with its importance score and the weighted
+// score components it is the sum of.
+type RankedThought struct {
+	Index      int                `json:"index"` // 1-based index of the thought
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components"`
+}
+
+// RankThoughts is a tool that ranks the thoughts by a heuristic importance
+// score. The score is the weighted sum, with the weights of -rank-weights, of
+// these components, each between 0 and 1 (or -1 and 1 for priority):
+//
+//   - priority: the priority relative to the largest absolute priority,
+//   - length: the length relative to the longest thought,
+//   - locked: 1 if the thought is locked,
+//   - resolved: 1 if the thought is resolved,
+//   - links: the number of thoughts that follow from or depend on it,
+//     relative to the most linked thought,
+//   - recency: when it was last touched, from 0 for the oldest to 1 for the
+//     most recent thought.
+func (t *ThinkTool) RankThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[RankThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	limit := params.Arguments.Limit
+	if limit < 0 {
+		return nil, toolError(CodeOutOfRange, "limit must not be negative")
+	}
+	if limit == 0 {
+		limit = 10
+	}
+
+	n := len(t.thoughts)
+	links := make([]int, n)
+	maxPriority, maxLength, maxLinks := 0, 0, 0
+	oldest, newest := t.thoughts[0].touchedAt(), t.thoughts[0].touchedAt()
+	for _, thought := range t.thoughts {
+		for _, target := range append([]int{thought.ParentIndex}, thought.DependsOn...) {
+			if target >= 1 && target <= n {
+				links[target-1]++
+			}
+		}
+		maxPriority = max(maxPriority, thought.Priority, -thought.Priority)
+		maxLength = max(maxLength, utf8.RuneCountInString(thought.Thought))
+		touched := thought.touchedAt()
+		if touched.Before(oldest) {
+			oldest = touched
+		}
+		if touched.After(newest) {
+			newest = touched
+		}
+	}
+	for _, l := range links {
+		maxLinks = max(maxLinks, l)
+	}
+	ratio := func(a, b float64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	}
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	weights := t.cfg.RankWeights
+	ranked := make([]RankedThought, n)
+	for i, thought := range t.thoughts {
+		raw := map[string]float64{
+			"priority": ratio(float64(thought.Priority), float64(maxPriority)),
+			"length":   ratio(float64(utf8.RuneCountInString(thought.Thought)), float64(maxLength)),
+			"locked":   flag(thought.Locked),
+			"resolved": flag(thought.Resolved),
+			"links":    ratio(float64(links[i]), float64(maxLinks)),
+			"recency":  ratio(float64(thought.touchedAt().Sub(oldest)), float64(newest.Sub(oldest))),
+		}
+		r := RankedThought{Index: i + 1, Components: map[string]float64{}}
+		for _, f := range rankFactors {
+			if c := weights[f] * raw[f]; c != 0 {
+				r.Components[f] = c
+				r.Score += c
+			}
+		}
+		ranked[i] = r
+	}
+	slices.SortStableFunc(ranked, func(a, b RankedThought) int { return cmp.Compare(b.Score, a.Score) })
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	sections := []string{}
+	for _, r := range ranked {
+		parts := []string{}
+		for _, f := range rankFactors {
+			if c, ok := r.Components[f]; ok {
+				parts = append(parts, fmt.Sprintf("%s %+.2f", f, c))
+			}
+		}
+		breakdown := strings.Join(parts, ", ")
+		if breakdown == "" {
+			breakdown = "no contributing factors"
+		}
+		sections = append(sections, fmt.Sprintf("Score %.2f (%s)\n%s", r.Score, breakdown, t.formatThought(r.Index-1, t.thoughts[r.Index-1])))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(sections, "\n")}},
+		StructuredContent: map[string]any{"ranking": ranked},
+	}, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AuditEvent is an entry of the audit log: a thought was added, updated or
+// deleted, the log was cleared or reordered.
+type AuditEvent struct {
+	Seq       int      `json:"seq"` // Position among all events since the start, from 1
+	Time      string   `json:"time"`
+	Op        string   `json:"op"` // add, update, delete, clear or order
+	ThoughtID string   `json:"thought_id,omitempty"`
+	IDs       []string `json:"ids,omitempty"`     // For order, the IDs of all thoughts in log order
+	Session   string   `json:"session,omitempty"` // ID of the session that made the change, empty in stdio mode and for expiry
+}
+
+// auditLog is a ring buffer of the latest audit events.
+type auditLog struct {
+	events []AuditEvent
+	next   int // Position of the oldest event once the buffer is full
+	seq    int // Number of events recorded so far
+}
+
+// add appends ev, overwriting the oldest event if size events are kept.
+func (a *auditLog) add(ev AuditEvent, size int) {
+	a.seq++
+	ev.Seq = a.seq
+	if len(a.events) < size {
+		a.events = append(a.events, ev)
+		return
+	}
+	a.events[a.next] = ev
+	a.next = (a.next + 1) % size
+}
+
+// list returns the kept events, oldest first.
+func (a *auditLog) list() []AuditEvent {
+	return append(append([]AuditEvent{}, a.events[a.next:]...), a.events[:a.next]...)
+}
+
+// sessionID returns the ID of sess, or "" if there is none.
+func sessionID(sess *mcp.ServerSession) string {
+	if sess == nil {
+		return ""
+	}
+	return sess.ID()
+}
+
+// recordAudit adds the changes of the log since the last call to the audit
+// log, attributed to sess. It must be called with t.mu held.
+func (t *ThinkTool) recordAudit(sess *mcp.ServerSession) {
+	if t.cfg.AuditSize <= 0 {
+		return
+	}
+	now := t.clock.Now().Format(time.RFC3339)
+	for _, ev := range journalEvents(t.audited, t.thoughts, now) {
+		entry := AuditEvent{Time: ev.Time, Op: ev.Op, ThoughtID: ev.ID, IDs: ev.IDs, Session: sessionID(sess)}
+		if ev.Thought != nil {
+			entry.ThoughtID = ev.Thought.ID
+		}
+		t.audit.add(entry, t.cfg.AuditSize)
+	}
+	t.audited = cloneThoughts(t.thoughts)
+}
+
+// ExportAuditLog is a tool that returns the audit log of the changes to the
+// log as JSON, oldest first. Only the latest -audit-size events are kept,
+// the number of older events that were dropped is reported along with them.
+func (t *ThinkTool) ExportAuditLog(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.cfg.AuditSize <= 0 {
+		return nil, toolError(CodeNotFound, "the audit log is disabled. Start the server with a positive -audit-size to use it.")
+	}
+	events := t.audit.list()
+	doc := map[string]any{"events": events, "dropped": t.audit.seq - len(events)}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to encode audit log: %v", err)
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: string(b)}}, StructuredContent: doc}, nil
+}
@@ -0,0 +1,293 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Config is the server configuration. It is populated from command line
+// flags and their mirroring environment variables.
+type Config struct {
+	Transport string // Either stdio or http
+	Addr      string // Listen address in HTTP mode
+	BaseURL   string // URL under which clients reach the server in HTTP mode, empty to derive it from Addr
+
+	Persist        string        // Persistence file, empty if thoughts are kept in memory only
+	FlushInterval  time.Duration // Interval at which changes are written to the persistence file, 0 to write every change
+	Journal        string        // Append-only JSONL journal of the changes to the log, empty if disabled
+	AuditSize      int           // Number of latest changes kept in memory for export_audit_log, 0 if disabled
+	ReviewFile     string        // Sidecar JSON file of reviewer notes by thought ID, empty if none
+	TailFile       string        // Human-readable log every recorded thought is appended to, empty if disabled
+	ThoughtSchema  string        // JSON schema recorded thoughts must conform to, empty if thoughts are free text
+	RenderTemplate string        // text/template of a thought in get_thoughts_templated, empty if disabled
+	ContextHeader  string        // Framing text of get_context_block
+	ResponsePrefix string        // Text placed before every tool result
+	ResponseSuffix string        // Text placed after every tool result
+	Locale         string        // Language of the response strings, e.g. de
+	Timezone       string        // IANA time zone calendar dates are computed in, e.g. Europe/Berlin
+	IdleWarn       time.Duration // Idle period after which a warning is logged, 0 if disabled
+	Sanitize       bool          // Strip escape sequences and invisible control characters from recorded thoughts
+	SingleLine     string        // What think does with multi-line thoughts: reject or flatten them, empty to accept them
+	Redact         patternList   // Additional patterns of secrets redacted by get_redacted_thoughts
+
+	ShareTTL time.Duration // Lifetime of the shares created by create_share_link, 0 if they never expire
+
+	AutoParent      bool      // Make every thought recorded without a parent follow the previous one
+	RequireCategory bool      // Reject thoughts recorded without a category
+	Categories      commaList // Allowed categories, empty if any category is allowed
+	TagAllowlist    commaList // Allowed tags, empty if any tag is allowed
+	PerTagLimit     int       // Maximum number of thoughts per tag, 0 if unlimited
+	PerTagMode      string    // What think does when a tag is full: evict the oldest thought or reject the new one
+
+	MaxConcurrency int // Maximum number of heavy tool calls running at once, 0 if unlimited
+	MaxFrameBytes  int // Size above which get_thoughts splits its output into several content blocks, 0 to never split
+
+	SessionTokenBudget int // Maximum estimated tokens a session may record until the log is cleared, 0 if unlimited
+
+	RankWeights rankWeights // Weights of the score components of rank_thoughts
+}
+
+// rankFactors are the score components of rank_thoughts.
+var rankFactors = []string{"priority", "length", "locked", "resolved", "links", "recency"}
+
+// rankWeights maps each of rankFactors to its weight. It is a flag.Value
+// written as a comma-separated list of factor=weight pairs.
+type rankWeights map[string]float64
+
+func (w rankWeights) String() string {
+	pairs := []string{}
+	for _, f := range rankFactors {
+		if weight, ok := w[f]; ok {
+			pairs = append(pairs, f+"="+strconv.FormatFloat(weight, 'g', -1, 64))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set overrides the weights of the given factors, the others keep their
+// weight.
+func (w *rankWeights) Set(value string) error {
+	weights := maps.Clone(*w)
+	if weights == nil {
+		weights = rankWeights{}
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		factor, weight, ok := strings.Cut(pair, "=")
+		factor = strings.TrimSpace(factor)
+		if !ok || !slices.Contains(rankFactors, factor) {
+			return fmt.Errorf("invalid weight %q, expected factor=weight with factor one of %s", pair, strings.Join(rankFactors, ", "))
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return fmt.Errorf("invalid weight %q: %w", pair, err)
+		}
+		weights[factor] = f
+	}
+	*w = weights
+	return nil
+}
+
+// commaList is a flag.Value written as a comma-separated list. Items are
+// trimmed and empty items are dropped.
+type commaList []string
+
+func (l *commaList) String() string { return strings.Join(*l, ",") }
+
+func (l *commaList) Set(value string) error {
+	items := commaList{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*l = items
+	return nil
+}
+
+// Modes of -per-tag-mode.
+const (
+	perTagEvict  = "evict"
+	perTagReject = "reject"
+)
+
+// Modes of -single-line.
+const (
+	singleLineReject  = "reject"
+	singleLineFlatten = "flatten"
+)
+
+// registerFlags binds the configuration to flags of fs.
+func (c *Config) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Transport, "transport", "stdio", "transport to serve MCP on: stdio or http")
+	fs.StringVar(&c.Addr, "addr", "localhost:8080", "listen address in http mode; MCP is served at /mcp, a read-only export at /export.json and single thoughts at /thoughts/<id>")
+	fs.StringVar(&c.BaseURL, "base-url", "", "public URL of the server in http mode used in the permalinks of get_thoughts_with_links, e.g. https://think.example.com (http://<addr> if empty)")
+	fs.StringVar(&c.Persist, "persist", "", "path to a JSON file used to persist thoughts across restarts (disabled if empty)")
+	fs.DurationVar(&c.FlushInterval, "flush-interval", 0, "coalesce writes to the persistence file and flush at most once per interval, e.g. 2s (write on every change if 0)")
+	fs.StringVar(&c.Journal, "journal", "", "path to an append-only JSONL file recording every addition, change and removal of thoughts, from which rebuild_from_journal can recover the log (disabled if empty)")
+	fs.IntVar(&c.AuditSize, "audit-size", 1000, "number of the latest changes of the log kept in memory for export_audit_log, older ones are dropped (disabled if 0)")
+	fs.StringVar(&c.TailFile, "tail-file", "", "path to a plain text file every recorded thought is appended to as a line with its time and index, for watching with tail -f; the file is never truncated or rotated by the server (disabled if empty)")
+	fs.StringVar(&c.ReviewFile, "review-file", "", `path to a JSON file mapping thought IDs to reviewer notes, e.g. {"3f2a...": ["unclear", "see #4"]}, merged into the output of get_annotated_thoughts`)
+	fs.StringVar(&c.ThoughtSchema, "thought-schema", "", "path to a JSON schema (draft 2020-12) that every recorded thought must be a conforming JSON document of (free text if empty)")
+	fs.StringVar(&c.RenderTemplate, "render-template", "", "Go text/template rendering each thought in get_thoughts_templated, with the fields .Index, .Timestamp, .Tags, .Text and .Item for the whole thought, e.g. '{{.Index}}. {{.Text}}'")
+	fs.StringVar(&c.ContextHeader, "context-header", defaultContextHeader, "framing text placed before the thoughts returned by get_context_block")
+	fs.StringVar(&c.ResponsePrefix, "response-prefix", "", "text placed before the text of every tool result, e.g. a disclaimer")
+	fs.StringVar(&c.ResponseSuffix, "response-suffix", "", "text placed after the text of every tool result")
+	fs.StringVar(&c.Locale, "locale", "en", "language of the response strings: en, de, fr, es, zh or ja (falls back to en)")
+	fs.StringVar(&c.Timezone, "timezone", "UTC", "IANA time zone in which get_thoughts_by_day computes calendar dates, e.g. Europe/Berlin, or Local for the zone of the host")
+	fs.DurationVar(&c.IdleWarn, "idle-warn", 0, "log a warning when no thought has been recorded for this long, e.g. 10m (disabled if 0)")
+	fs.BoolVar(&c.Sanitize, "sanitize", false, "strip ANSI escape sequences and invisible control characters from recorded thoughts, keeping the original text in the raw field")
+	fs.StringVar(&c.SingleLine, "single-line", "", "enforce single-line thoughts for log sinks that require them: reject refuses multi-line thoughts, flatten joins their lines with spaces and keeps the original text in the raw field (multi-line thoughts are accepted if empty)")
+	fs.Var(&c.Redact, "redact", "regular expression of a secret that get_redacted_thoughts replaces in addition to the built-in patterns, may be repeated")
+	fs.DurationVar(&c.ShareTTL, "share-ttl", 0, "lifetime of the shares created by create_share_link, e.g. 24h (never expire if 0)")
+	fs.BoolVar(&c.AutoParent, "auto-parent", false, "make every thought recorded without a parent_index follow the previous thought, building a linear chain; an explicit parent_index takes precedence")
+	fs.BoolVar(&c.RequireCategory, "require-category", false, "reject thoughts recorded without a category")
+	fs.Var(&c.Categories, "categories", "comma-separated list of the categories thoughts may be recorded with, e.g. plan,risk,decision (any category if empty)")
+	fs.Var(&c.TagAllowlist, "tag-allowlist", "comma-separated list of the tags thoughts may be given, e.g. plan,risk,decision; tags are compared in lowercase (any tag if empty)")
+	fs.IntVar(&c.PerTagLimit, "per-tag-limit", 0, "maximum number of thoughts with the same tag, see -per-tag-mode for what happens beyond it (unlimited if 0)")
+	fs.StringVar(&c.PerTagMode, "per-tag-mode", perTagEvict, "what think does with a thought whose tag already has -per-tag-limit thoughts: evict removes the oldest unlocked thought with the tag, reject refuses the new thought")
+	fs.IntVar(&c.MaxFrameBytes, "max-frame-bytes", 1<<20, "split get_thoughts output larger than this many bytes into several content blocks (never split if 0)")
+	fs.IntVar(&c.SessionTokenBudget, "session-token-budget", 0, "maximum estimated number of tokens of the thoughts a session may record before the log is cleared, further thoughts are refused (unlimited if 0)")
+	c.RankWeights = rankWeights{"priority": 1, "length": 0.5, "locked": 1, "resolved": -1, "links": 0.5, "recency": 1}
+	fs.Var(&c.RankWeights, "rank-weights", "weights of the score components of rank_thoughts as factor=weight pairs, unlisted factors keep their default: "+strings.Join(rankFactors, ", "))
+	fs.IntVar(&c.MaxConcurrency, "max-concurrency", 0, "maximum number of expensive tool calls (exports, searches, bulk edits) running at once, excess calls are rejected as busy (unlimited if 0)")
+}
+
+// validate reports the first invalid setting of the configuration.
+func (c *Config) validate() error {
+	switch c.Transport {
+	case "stdio", "http":
+	default:
+		return fmt.Errorf("invalid transport %q, expected stdio or http", c.Transport)
+	}
+	if c.BaseURL != "" {
+		if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid base URL %q, expected an http(s) URL", c.BaseURL)
+		}
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	switch c.SingleLine {
+	case "", singleLineReject, singleLineFlatten:
+	default:
+		return fmt.Errorf("invalid single-line mode %q, expected %s or %s", c.SingleLine, singleLineReject, singleLineFlatten)
+	}
+	switch c.PerTagMode {
+	case perTagEvict, perTagReject:
+	default:
+		return fmt.Errorf("invalid per-tag mode %q, expected %s or %s", c.PerTagMode, perTagEvict, perTagReject)
+	}
+	if _, err := parseRenderTemplate(c.RenderTemplate); err != nil {
+		return fmt.Errorf("invalid render template: %w", err)
+	}
+	return nil
+}
+
+// envName returns the environment variable that mirrors the given flag, e.g.
+// THINK_IDLE_WARN for -idle-warn.
+func envName(flagName string) string {
+	return "THINK_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets every flag of fs that was not given on the command line from
+// its mirroring environment variable, if present. Flags given on the command
+// line take precedence over the environment.
+func applyEnv(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s: %w", value, name, e)
+		}
+	})
+	return err
+}
+
+// sensitiveFlags are the flags whose values get_config never reveals. The
+// patterns of -redact describe the secrets to hide and may contain them.
+var sensitiveFlags = []string{"redact"}
+
+// redactedValue replaces the value of a sensitive flag in get_config.
+const redactedValue = "[REDACTED]"
+
+// Setting is a configuration option as reported by get_config.
+type Setting struct {
+	Flag    string `json:"flag"`
+	Env     string `json:"env"` // The mirroring environment variable
+	Value   string `json:"value"`
+	Default string `json:"default"`
+	Changed bool   `json:"changed"` // Whether the value differs from the default
+}
+
+// settings returns every option of the configuration with its effective
+// value, in flag order. The values of sensitiveFlags are redacted.
+func (c Config) settings() []Setting {
+	// Binding the flags to a copy of c makes every flag.Value report the
+	// effective value, while the flags keep their defaults.
+	var effective Config
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	effective.registerFlags(fs)
+	effective = c
+
+	settings := []Setting{}
+	fs.VisitAll(func(f *flag.Flag) {
+		s := Setting{Flag: f.Name, Env: envName(f.Name), Value: f.Value.String(), Default: f.DefValue}
+		s.Changed = s.Value != s.Default
+		if slices.Contains(sensitiveFlags, f.Name) {
+			if s.Value != "" {
+				s.Value = redactedValue
+			}
+			if s.Default != "" {
+				s.Default = redactedValue
+			}
+		}
+		settings = append(settings, s)
+	})
+	return settings
+}
+
+// GetConfig is a tool that reports the effective configuration of the server,
+// i.e. the flags after applying their environment variables. Sensitive values
+// are redacted.
+func (t *ThinkTool) GetConfig(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	settings := t.cfg.settings()
+	lines := []string{"Effective configuration (* marks values changed from the default):"}
+	for _, s := range settings {
+		mark := " "
+		if s.Changed {
+			mark = "*"
+		}
+		lines = append(lines, fmt.Sprintf("%s -%s=%q (%s)", mark, s.Flag, s.Value, s.Env))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"settings": settings},
+	}, nil
+}
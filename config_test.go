@@ -0,0 +1,112 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		check   func(c Config) any // Selects the setting under test
+		want    any
+		wantErr string // Substring of the expected error, empty if none
+	}{
+		{
+			name:  "env sets an unset flag",
+			env:   map[string]string{"THINK_FLUSH_INTERVAL": "2s"},
+			check: func(c Config) any { return c.FlushInterval },
+			want:  2 * time.Second,
+		},
+		{
+			name:  "flag takes precedence over env",
+			args:  []string{"-flush-interval=5s"},
+			env:   map[string]string{"THINK_FLUSH_INTERVAL": "2s"},
+			check: func(c Config) any { return c.FlushInterval },
+			want:  5 * time.Second,
+		},
+		{
+			name:  "default without flag or env",
+			check: func(c Config) any { return c.Locale },
+			want:  "en",
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"THINK_FLUSH_INTERVAL": "abc"},
+			wantErr: `invalid value "abc" for environment variable THINK_FLUSH_INTERVAL`,
+		},
+		{
+			name:    "invalid boolean",
+			env:     map[string]string{"THINK_SANITIZE": "maybe"},
+			wantErr: "THINK_SANITIZE",
+		},
+		{
+			name:  "repeatable flag from env",
+			env:   map[string]string{"THINK_REDACT": `token-\d+`},
+			check: func(c Config) any { return c.Redact },
+			want:  patternList{`token-\d+`},
+		},
+		{
+			name:  "repeatable flag given on the command line ignores env",
+			args:  []string{"-redact=a+", "-redact=b+"},
+			env:   map[string]string{"THINK_REDACT": "c+"},
+			check: func(c Config) any { return c.Redact },
+			want:  patternList{"a+", "b+"},
+		},
+		{
+			name:    "invalid pattern of a repeatable flag",
+			env:     map[string]string{"THINK_REDACT": "("},
+			wantErr: "THINK_REDACT",
+		},
+		{
+			name:  "comma-separated list from env",
+			env:   map[string]string{"THINK_CATEGORIES": "plan, risk,,decision"},
+			check: func(c Config) any { return c.Categories },
+			want:  commaList{"plan", "risk", "decision"},
+		},
+		{
+			name:  "multi-word flag name",
+			env:   map[string]string{"THINK_SESSION_TOKEN_BUDGET": "1000"},
+			check: func(c Config) any { return c.SessionTokenBudget },
+			want:  1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var cfg Config
+			fs := flag.NewFlagSet("think-tool", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			cfg.registerFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+
+			err := applyEnv(fs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyEnv returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEnv failed: %v", err)
+			}
+			if got := tt.check(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrorCode classifies a tool error so that clients can branch on the cause
+// of a failure instead of parsing the message.
+type ErrorCode string
+
+const (
+	CodeEmptyInput     ErrorCode = "EMPTY_INPUT"     // A required input is missing or empty
+	CodeInvalidInput   ErrorCode = "INVALID_INPUT"   // An input is malformed or not allowed
+	CodeOutOfRange     ErrorCode = "OUT_OF_RANGE"    // An index or cursor does not refer to a thought
+	CodeNotFound       ErrorCode = "NOT_FOUND"       // The requested entity does not exist
+	CodeNoThoughts     ErrorCode = "NO_THOUGHTS"     // The log is empty
+	CodeBusy           ErrorCode = "BUSY"            // The server is at capacity, retry later
+	CodeBudgetExceeded ErrorCode = "BUDGET_EXCEEDED" // A configured budget would be exceeded
+	CodeLocked         ErrorCode = "LOCKED"          // The thought is locked against edits
+	CodeInternal       ErrorCode = "INTERNAL"        // The server failed to process the request
+)
+
+// ToolError is an error returned by a tool. It is reported to the client as
+// an error result whose structured content carries the code and message.
+type ToolError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+func (e *ToolError) Error() string { return e.Message }
+
+// toolError returns a ToolError with the given code and formatted message.
+func toolError(code ErrorCode, format string, args ...any) error {
+	return &ToolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errNoThoughts returns the error for tools that need at least one thought.
+func (t *ThinkTool) errNoThoughts() error {
+	return toolError(CodeNoThoughts, "%s", t.msg("error.no_thoughts"))
+}
+
+// withErrorCodes converts the errors returned by h into error results with a
+// structured error code. Errors that are not a ToolError are reported as
+// INTERNAL.
+func withErrorCodes[In any](h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[any], error) {
+		res, err := h(ctx, sess, params)
+		if err == nil {
+			return res, nil
+		}
+
+		var te *ToolError
+		if !errors.As(err, &te) {
+			te = &ToolError{Code: CodeInternal, Message: err.Error()}
+		}
+		return &mcp.CallToolResultFor[any]{
+			Content:           []mcp.Content{&mcp.TextContent{Text: te.Message}},
+			StructuredContent: te,
+			IsError:           true,
+		}, nil
+	}
+}
@@ -0,0 +1,772 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ExportInput struct {
+	MaxBytes int    `json:"max_bytes,omitempty" jsonschema:"maximum size of the exported page in bytes, 0 exports everything at once"`
+	Cursor   string `json:"cursor,omitempty" jsonschema:"continuation token returned by a previous call to fetch the next page"`
+	AndClear bool   `json:"and_clear,omitempty" jsonschema:"clear the log in the same operation after exporting it, so that no thought is lost or recorded in between"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"with and_clear, confirm clearing the log even though it contains locked thoughts"`
+}
+
+type ExportJSONInput struct {
+	MaxBytes int    `json:"max_bytes,omitempty" jsonschema:"maximum size of the exported page in bytes, 0 exports everything at once"`
+	Cursor   string `json:"cursor,omitempty" jsonschema:"continuation token returned by a previous call to fetch the next page"`
+	Checksum bool   `json:"checksum,omitempty" jsonschema:"embed a checksum of the exported thoughts that import_thoughts verifies"`
+	AndClear bool   `json:"and_clear,omitempty" jsonschema:"clear the log in the same operation after exporting it, so that no thought is lost or recorded in between"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"with and_clear, confirm clearing the log even though it contains locked thoughts"`
+}
+
+// ExportAndClearInput is the input of the exports without other options.
+type ExportAndClearInput struct {
+	AndClear bool `json:"and_clear,omitempty" jsonschema:"clear the log in the same operation after exporting it, so that no thought is lost or recorded in between"`
+	Confirm  bool `json:"confirm,omitempty" jsonschema:"with and_clear, confirm clearing the log even though it contains locked thoughts"`
+}
+
+// errPagedClear is the error for and_clear combined with a paged export.
+func errPagedClear() error {
+	return toolError(CodeInvalidInput, "and_clear cannot be combined with max_bytes or cursor, the log must be exported at once to be cleared")
+}
+
+// checkAndClear reports why an export cannot clear the log with and_clear,
+// or nil if it can or andClear is not set. Like clear_thoughts, it refuses to
+// clear locked thoughts unless confirm is set. It must be called with t.mu
+// held, before exporting, so that a refused export changes nothing.
+func (t *ThinkTool) checkAndClear(andClear, confirm bool) error {
+	if !andClear {
+		return nil
+	}
+	return t.checkClear(confirm)
+}
+
+// exported returns the result of an export. With andClear, the log is
+// cleared like clear_thoughts with keep_goal set, and a second content block
+// reports it, keeping the export itself intact. It must be called with t.mu
+// held, in the same critical section as the export and checkAndClear, and
+// with the write lock if andClear is set.
+func (t *ThinkTool) exported(sess *mcp.ServerSession, text string, andClear bool) *mcp.CallToolResultFor[any] {
+	content := []mcp.Content{&mcp.TextContent{Text: text}}
+	if andClear {
+		n := len(t.thoughts)
+		t.lastCleared = t.thoughts
+		t.thoughts = []ThoughtItem{}
+		t.resetSessionTokens()
+		warning := t.persist(sess)
+		content = append(content, &mcp.TextContent{Text: fmt.Sprintf("Cleared the %d exported thought(s).%s", n, warning)})
+	}
+	return &mcp.CallToolResultFor[any]{Content: content}
+}
+
+// exportDoc is the JSON document produced by ExportJSON and consumed by
+// ImportThoughts.
+type exportDoc struct {
+	Thoughts   []ThoughtItem `json:"thoughts"`
+	Checksum   string        `json:"checksum,omitempty"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// checksum returns the checksum of the thoughts as embedded in an export.
+func checksum(thoughts []ThoughtItem) (string, error) {
+	b, err := json.Marshal(thoughts)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// page renders the page of thoughts after the thought whose ID is cursor, or
+// from the first thought if cursor is empty. render renders the thoughts
+// [start, end) along with next, the cursor of the following page, which is
+// empty on the last page. With a positive maxBytes, the page is the longest
+// one whose rendering fits into maxBytes, but holds at least one thought so
+// that an oversized thought cannot stall the export. The cursor is the ID of
+// the last thought of a page, so thoughts that are added or removed between
+// calls do not shift the following pages.
+func (t *ThinkTool) page(cursor string, maxBytes int, render func(start, end int, next string) (string, error)) (string, error) {
+	start := 0
+	if cursor != "" {
+		i := slices.IndexFunc(t.thoughts, func(item ThoughtItem) bool { return item.ID == cursor })
+		if i < 0 {
+			return "", toolError(CodeNotFound, "no thought with ID %q, it may have been removed. Start the export again without cursor.", cursor)
+		}
+		start = i + 1
+	}
+	n := len(t.thoughts)
+	if maxBytes <= 0 || start == n {
+		return render(start, n, "")
+	}
+
+	// The last page has no cursor and may fit although shorter pages with a
+	// cursor do not, so it is tried first. The pages with a cursor grow with
+	// their end and are searched by bisection.
+	if text, err := render(start, n, ""); err != nil || len(text) <= maxBytes || start == n-1 {
+		return text, err
+	}
+	var failed error
+	end := start + 1 + sort.Search(n-start-2, func(i int) bool {
+		end := start + 2 + i
+		text, err := render(start, end, t.thoughts[end-1].ID)
+		if err != nil {
+			failed = err
+			return true
+		}
+		return len(text) > maxBytes
+	})
+	if failed != nil {
+		return "", failed
+	}
+	return render(start, end, t.thoughts[end-1].ID)
+}
+
+// ExportJSON is a tool that exports the thoughts as a JSON document.
+func (t *ThinkTool) ExportJSON(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportJSONInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	args := params.Arguments
+	if args.AndClear && (args.MaxBytes > 0 || args.Cursor != "") {
+		return nil, errPagedClear()
+	}
+	text, err := t.page(args.Cursor, args.MaxBytes, func(start, end int, next string) (string, error) {
+		doc := exportDoc{Thoughts: append([]ThoughtItem{}, t.thoughts[start:end]...), NextCursor: next}
+		if args.Checksum {
+			sum, err := checksum(doc.Thoughts)
+			if err != nil {
+				return "", toolError(CodeInternal, "failed to compute checksum: %v", err)
+			}
+			doc.Checksum = sum
+		}
+		b, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return "", toolError(CodeInternal, "failed to encode thoughts: %v", err)
+		}
+		return string(b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.exported(sess, text, args.AndClear), nil
+}
+
+type SyncSinceInput struct {
+	Fingerprint string `json:"fingerprint,omitempty" jsonschema:"the fingerprint returned by the previous sync_since call, empty on the first call"`
+}
+
+// SyncSince is a tool for clients keeping a copy of the log. The fingerprint
+// of the log is its checksum as embedded by export_json. If the given
+// fingerprint is the current one, only that is reported; otherwise, including
+// for unknown or empty fingerprints, the whole log is returned as an
+// export_json document carrying the new fingerprint as its checksum.
+func (t *ThinkTool) SyncSince(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SyncSinceInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	fingerprint, err := checksum(t.thoughts)
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to compute fingerprint: %v", err)
+	}
+	if strings.TrimSpace(params.Arguments.Fingerprint) == fingerprint {
+		return &mcp.CallToolResultFor[any]{
+			Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No changes since %s.", fingerprint)}},
+			StructuredContent: map[string]any{"changed": false, "fingerprint": fingerprint},
+		}, nil
+	}
+
+	doc := exportDoc{Thoughts: append([]ThoughtItem{}, t.thoughts...), Checksum: fingerprint}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to encode thoughts: %v", err)
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("The log has changed, the new fingerprint is %s.", fingerprint)},
+			&mcp.TextContent{Text: string(b)},
+		},
+		StructuredContent: map[string]any{"changed": true, "fingerprint": fingerprint},
+	}, nil
+}
+
+type ImportThoughtsInput struct {
+	Data string `json:"data" jsonschema:"a JSON document as produced by export_json"`
+}
+
+// ImportThoughts is a tool that appends the thoughts of a JSON export to the
+// log. If the export carries a checksum, the whole batch is rejected unless
+// the checksum matches. It is also rejected if any thought would not be
+// accepted by think.
+func (t *ThinkTool) ImportThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ImportThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if strings.TrimSpace(params.Arguments.Data) == "" {
+		return nil, toolError(CodeEmptyInput, "no data provided")
+	}
+	var doc exportDoc
+	if err := json.Unmarshal([]byte(params.Arguments.Data), &doc); err != nil {
+		return nil, toolError(CodeInvalidInput, "invalid export: %v", err)
+	}
+	if doc.Checksum != "" {
+		sum, err := checksum(doc.Thoughts)
+		if err != nil {
+			return nil, toolError(CodeInternal, "failed to compute checksum: %v", err)
+		}
+		if sum != doc.Checksum {
+			return nil, toolError(CodeInvalidInput, "checksum mismatch: the export declares %s but its thoughts hash to %s; the data may be truncated or modified", doc.Checksum, sum)
+		}
+	}
+	for i, thought := range doc.Thoughts {
+		if strings.TrimSpace(thought.Thought) == "" {
+			return nil, toolError(CodeInvalidInput, "thought #%d of the export has no text", i+1)
+		}
+		if err := t.checkImported(thought); err != nil {
+			return nil, toolError(CodeInvalidInput, "thought #%d of the export is rejected: %v", i+1, err)
+		}
+	}
+
+	n := t.appendImported(doc.Thoughts)
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Imported %d thought(s).%s", n, warning)}}}, nil
+}
+
+// checkImported checks a thought from another log against the same settings
+// as a thought recorded with think: -thought-schema, -require-category,
+// -categories and -tag-allowlist. Importing must not be a way around them.
+func (t *ThinkTool) checkImported(item ThoughtItem) error {
+	if err := t.checkSchema(item.Thought); err != nil {
+		return err
+	}
+	if _, err := t.checkCategory(item.Category); err != nil {
+		return err
+	}
+	return t.checkTags(cleanTags(item.Tags))
+}
+
+// appendImported appends thoughts from another log and returns how many were
+// appended. Parent and dependency links are kept if they point inside the
+// batch and dropped otherwise. Thoughts without an ID, or whose ID is already
+// taken, get a new one. The thoughts must have passed checkImported. It must
+// be called with t.mu held.
+func (t *ThinkTool) appendImported(thoughts []ThoughtItem) int {
+	ids := map[string]bool{}
+	for _, thought := range t.thoughts {
+		ids[thought.ID] = true
+	}
+
+	offset := len(t.thoughts)
+	now := t.clock.Now().Format(time.RFC3339)
+	for _, item := range thoughts {
+		item = item.clone()
+		item.Tags = cleanTags(item.Tags)
+		item.Category = strings.TrimSpace(item.Category)
+		if item.ID == "" || ids[item.ID] {
+			item.ID = newID()
+		}
+		ids[item.ID] = true
+		if item.CreatedAt == "" {
+			item.CreatedAt = now
+		}
+		item.relink(func(index int) int {
+			if index > len(thoughts) {
+				return 0
+			}
+			return index + offset
+		})
+		t.thoughts = append(t.thoughts, item)
+	}
+	return len(thoughts)
+}
+
+// markdownSection renders the thought at index i of the log as a Markdown
+// section.
+func markdownSection(i int, item ThoughtItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Thought #%d\n\n_Recorded at %s_\n\n%s\n\n", i+1, item.CreatedAt, item.Thought)
+	for _, note := range item.Notes {
+		fmt.Fprintf(&b, "> Note: %s\n\n", note)
+	}
+	return b.String()
+}
+
+// ExportMarkdown is a tool that exports the thoughts as a Markdown document.
+func (t *ThinkTool) ExportMarkdown(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	args := params.Arguments
+	if args.AndClear && (args.MaxBytes > 0 || args.Cursor != "") {
+		return nil, errPagedClear()
+	}
+	text, err := t.page(args.Cursor, args.MaxBytes, func(start, end int, next string) (string, error) {
+		var b strings.Builder
+		b.WriteString("# Thoughts\n\n")
+		for i := start; i < end; i++ {
+			b.WriteString(markdownSection(i, t.thoughts[i]))
+		}
+		if next != "" {
+			fmt.Fprintf(&b, "---\n\n_Export truncated after thought #%d of %d. Call again with cursor %q to continue._\n", end, len(t.thoughts), next)
+		}
+		return b.String(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.exported(sess, text, args.AndClear), nil
+}
+
+// ExportIssueBody is a tool that exports the thoughts as the body of a GitHub
+// issue: a summary header, a task list of the thoughts if any of them has
+// been resolved, and a collapsible <details> section per thought. Text is
+// HTML-escaped so that it cannot break out of the sections, and thoughts are
+// not numbered as #N, which GitHub would link to other issues.
+func (t *ThinkTool) ExportIssueBody(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	resolved := 0
+	for _, thought := range t.thoughts {
+		if thought.Resolved {
+			resolved++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("## Summary\n\n")
+	if t.goal != "" {
+		fmt.Fprintf(&b, "**Goal:** %s\n\n", html.EscapeString(strings.Join(strings.Fields(t.goal), " ")))
+	}
+	fmt.Fprintf(&b, "%d thought(s) recorded from %s to %s", len(t.thoughts), t.thoughts[0].CreatedAt, t.thoughts[len(t.thoughts)-1].CreatedAt)
+	if resolved > 0 {
+		fmt.Fprintf(&b, ", %d resolved and %d open", resolved, len(t.thoughts)-resolved)
+	}
+	b.WriteString(".\n\n")
+
+	if resolved > 0 {
+		b.WriteString("## Tasks\n\n")
+		for i, thought := range t.thoughts {
+			box := " "
+			if thought.Resolved {
+				box = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] Thought %d: %s\n", box, i+1, html.EscapeString(headline(thought.Thought, headlineWords)))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Thoughts\n\n")
+	for i, thought := range t.thoughts {
+		fmt.Fprintf(&b, "<details>\n<summary>Thought %d: %s</summary>\n\n", i+1, html.EscapeString(headline(thought.Thought, headlineWords)))
+		fmt.Fprintf(&b, "_Recorded at %s_\n\n%s\n\n", thought.CreatedAt, html.EscapeString(strings.TrimSpace(thought.Thought)))
+		if len(thought.Tags) > 0 {
+			fmt.Fprintf(&b, "Tags: %s\n\n", html.EscapeString(strings.Join(thought.Tags, ", ")))
+		}
+		for _, note := range thought.Notes {
+			fmt.Fprintf(&b, "> %s\n\n", html.EscapeString(strings.Join(strings.Fields(note), " ")))
+		}
+		b.WriteString("</details>\n\n")
+	}
+	return t.exported(sess, strings.TrimSuffix(b.String(), "\n"), params.Arguments.AndClear), nil
+}
+
+// defaultContextHeader frames the thoughts returned by GetContextBlock.
+const defaultContextHeader = "Previously recorded reasoning (do not repeat):"
+
+type GetContextBlockInput struct {
+	Header   string `json:"header,omitempty" jsonschema:"framing text placed before the thoughts, defaults to the server's configured header"`
+	MaxBytes int    `json:"max_bytes,omitempty" jsonschema:"maximum size of the block in bytes, the oldest thoughts are dropped first; 0 means no limit"`
+}
+
+// GetContextBlock is a tool that returns the thoughts as a single block that
+// is ready to be injected into a prompt.
+func (t *ThinkTool) GetContextBlock(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetContextBlockInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	header := params.Arguments.Header
+	if header == "" {
+		header = t.cfg.ContextHeader
+	}
+	if header == "" {
+		header = defaultContextHeader
+	}
+
+	lines := make([]string, len(t.thoughts))
+	for i, thought := range t.thoughts {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, thought.Thought)
+	}
+
+	// Keep the most recent thoughts that fit into the budget.
+	start := 0
+	if max := params.Arguments.MaxBytes; max > 0 {
+		size := len(header) + 1
+		for start = len(lines); start > 0; start-- {
+			if size+len(lines[start-1])+1 > max {
+				break
+			}
+			size += len(lines[start-1]) + 1
+		}
+		if start == len(lines) {
+			return nil, toolError(CodeOutOfRange, "max_bytes %d is too small to hold the most recent thought", max)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString("\n")
+	if start > 0 {
+		fmt.Fprintf(&b, "(%d earlier thought(s) omitted)\n", start)
+	}
+	b.WriteString(strings.Join(lines[start:], "\n"))
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: b.String()}}}, nil
+}
+
+// ExportCSV is a tool that exports the thoughts as CSV. Tags are joined with
+// commas in a single column.
+func (t *ThinkTool) ExportCSV(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"index", "created_at", "tags", "thought"})
+	for i, thought := range t.thoughts {
+		w.Write([]string{strconv.Itoa(i + 1), thought.CreatedAt, strings.Join(thought.Tags, ","), thought.Thought})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, toolError(CodeInternal, "failed to encode thoughts: %v", err)
+	}
+	return t.exported(sess, b.String(), params.Arguments.AndClear), nil
+}
+
+type ExportThoughtChainInput struct {
+	Index int `json:"index" jsonschema:"the 1-based index of the thought whose chain to export"`
+}
+
+// ExportThoughtChain is a tool that exports a thought and the chain of
+// thoughts it follows from as a Markdown document.
+func (t *ThinkTool) ExportThoughtChain(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportThoughtChainInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	i, err := t.thoughtAt(params.Arguments.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk up the parent links. Links are validated when recorded, but the
+	// visited set keeps a corrupted store from looping forever.
+	chain := []int{}
+	visited := map[int]bool{}
+	for i >= 0 && i < len(t.thoughts) && !visited[i] {
+		visited[i] = true
+		chain = append(chain, i)
+		i = t.thoughts[i].ParentIndex - 1
+	}
+	slices.Reverse(chain)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Reasoning chain for thought #%d\n\n", params.Arguments.Index)
+	for _, i := range chain {
+		b.WriteString(markdownSection(i, t.thoughts[i]))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: b.String()}}}, nil
+}
+
+// mermaidLabel escapes text for use inside a quoted Mermaid node label.
+var mermaidLabel = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"\r", " ",
+	"\n", " ",
+)
+
+// ExportMermaid is a tool that exports the thoughts as a Mermaid flowchart
+// with an edge from each thought to the thoughts that follow from or depend
+// on it.
+func (t *ThinkTool) ExportMermaid(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for i, thought := range t.thoughts {
+		label := mermaidLabel.Replace(fmt.Sprintf("#%d: %s", i+1, truncate(thought.Thought, 40)))
+		fmt.Fprintf(&b, "    T%d[\"%s\"]\n", i+1, label)
+	}
+	for i, thought := range t.thoughts {
+		if thought.ParentIndex > 0 {
+			fmt.Fprintf(&b, "    T%d --> T%d\n", thought.ParentIndex, i+1)
+		}
+		for _, d := range thought.DependsOn {
+			fmt.Fprintf(&b, "    T%d -.-> T%d\n", d, i+1)
+		}
+	}
+	return t.exported(sess, b.String(), params.Arguments.AndClear), nil
+}
+
+// otelEvent is a span event in the OTLP JSON encoding.
+type otelEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otelAttribute `json:"attributes"`
+}
+
+// otelAttribute is a key-value pair in the OTLP JSON encoding, where value
+// is an AnyValue such as {"stringValue": "..."}.
+type otelAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otelString(key, value string) otelAttribute {
+	return otelAttribute{Key: key, Value: map[string]any{"stringValue": value}}
+}
+
+// otelInt encodes an integer attribute. OTLP JSON encodes 64-bit integers
+// as strings.
+func otelInt(key string, value int) otelAttribute {
+	return otelAttribute{Key: key, Value: map[string]any{"intValue": strconv.Itoa(value)}}
+}
+
+// ExportOTelEvents is a tool that exports the thoughts as OpenTelemetry span
+// events in the OTLP JSON encoding, one event per thought at the time it was
+// recorded. It only serializes the log; shipping the events is up to the
+// client.
+func (t *ThinkTool) ExportOTelEvents(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	events := []otelEvent{}
+	for i, thought := range t.thoughts {
+		var nanos int64
+		if created, err := time.Parse(time.RFC3339, thought.CreatedAt); err == nil {
+			nanos = created.UnixNano()
+		}
+		attrs := []otelAttribute{
+			otelInt("thought.index", i+1),
+			otelString("thought.id", thought.ID),
+			otelString("thought.text", thought.Thought),
+		}
+		if len(thought.Tags) > 0 {
+			tags := []map[string]any{}
+			for _, tag := range thought.Tags {
+				tags = append(tags, map[string]any{"stringValue": tag})
+			}
+			attrs = append(attrs, otelAttribute{Key: "thought.tags", Value: map[string]any{"arrayValue": map[string]any{"values": tags}}})
+		}
+		if thought.Category != "" {
+			attrs = append(attrs, otelString("thought.category", thought.Category))
+		}
+		if thought.Priority != 0 {
+			attrs = append(attrs, otelInt("thought.priority", thought.Priority))
+		}
+		if thought.ParentIndex > 0 {
+			attrs = append(attrs, otelInt("thought.parent_index", thought.ParentIndex))
+		}
+		if thought.Resolved {
+			attrs = append(attrs, otelAttribute{Key: "thought.resolved", Value: map[string]any{"boolValue": true}})
+		}
+		events = append(events, otelEvent{
+			TimeUnixNano: strconv.FormatInt(nanos, 10),
+			Name:         "thought",
+			Attributes:   attrs,
+		})
+	}
+	b, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to encode thoughts: %v", err)
+	}
+	return t.exported(sess, string(b), params.Arguments.AndClear), nil
+}
+
+type ExportFlashcardsInput struct {
+	Delimiter string `json:"delimiter,omitempty" jsonschema:"text separating the question from the answer in a thought, defaults to ?; the default question mark stays on the question"`
+	AndClear  bool   `json:"and_clear,omitempty" jsonschema:"clear the log in the same operation after exporting it, so that no thought is lost or recorded in between"`
+	Confirm   bool   `json:"confirm,omitempty" jsonschema:"with and_clear, confirm clearing the log even though it contains locked thoughts"`
+}
+
+// ankiField escapes text for a field of an Anki import in HTML mode: HTML
+// special characters are escaped, newlines become line breaks and tabs,
+// which separate the fields, become spaces.
+func ankiField(text string) string {
+	text = html.EscapeString(strings.TrimSpace(text))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n", "<br>")
+	return strings.ReplaceAll(text, "\t", " ")
+}
+
+// ExportFlashcards is a tool that exports the thoughts as a deck of
+// flashcards in Anki's tab-separated text format. A thought is split into a
+// question on the front and an answer on the back at the first delimiter.
+// Thoughts without the delimiter, or with nothing after it, get their
+// headline on the front and their whole text on the back.
+func (t *ThinkTool) ExportFlashcards(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportFlashcardsInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	delim, keep := params.Arguments.Delimiter, false
+	if delim == "" {
+		delim, keep = "?", true
+	}
+
+	var b strings.Builder
+	b.WriteString("#separator:tab\n#html:true\n")
+	for _, thought := range t.thoughts {
+		front, back, ok := strings.Cut(thought.Thought, delim)
+		if !ok || strings.TrimSpace(back) == "" || strings.TrimSpace(front) == "" {
+			front, back = headline(thought.Thought, headlineWords), thought.Thought
+		} else if keep {
+			front += delim
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", ankiField(front), ankiField(back))
+	}
+	return t.exported(sess, b.String(), params.Arguments.AndClear), nil
+}
+
+// ExportYAML is a tool that exports the thoughts as a YAML sequence with the
+// same keys as export_json. Multi-line thoughts are written as literal block
+// scalars so that they stay readable.
+func (t *ThinkTool) ExportYAML(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	return t.exported(sess, thoughtsYAML(t.thoughts), params.Arguments.AndClear), nil
+}
+
+// atomFeed is an Atom feed (RFC 4287).
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// ExportFeed is a tool that exports the thoughts as an Atom feed, one entry
+// per thought, newest first. Entries are identified by thought ID so that
+// feed readers recognize them across exports.
+func (t *ThinkTool) ExportFeed(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExportAndClearInput]) (*mcp.CallToolResultFor[any], error) {
+	defer t.lock(params.Arguments.AndClear)()
+
+	if err := t.checkAndClear(params.Arguments.AndClear, params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	feed := atomFeed{
+		ID:     "urn:" + serverName + ":thoughts",
+		Title:  serverName,
+		Author: atomAuthor{Name: serverName},
+	}
+	var updated time.Time
+	for i := len(t.thoughts) - 1; i >= 0; i-- {
+		thought := t.thoughts[i]
+		touched := thought.touchedAt()
+		if touched.After(updated) {
+			updated = touched
+		}
+		entry := atomEntry{
+			ID:        "urn:" + serverName + ":thought:" + thought.ID,
+			Title:     fmt.Sprintf("#%d %s", i+1, headline(thought.Thought, headlineWords)),
+			Published: thought.CreatedAt,
+			Updated:   touched.Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Text: thought.Thought},
+		}
+		if thought.Category != "" {
+			entry.Categories = append(entry.Categories, atomCategory{Term: thought.Category})
+		}
+		for _, tag := range thought.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = t.clock.Now()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to encode thoughts: %v", err)
+	}
+	return t.exported(sess, xml.Header+string(b), params.Arguments.AndClear), nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestImportThoughtsChecksTags(t *testing.T) {
+	tool := NewThinkTool(Config{TagAllowlist: commaList{"plan"}}, nil)
+
+	data := `{"thoughts": [{"thought": "allowed", "tags": ["Plan"]}, {"thought": "smuggled", "tags": ["secret"]}]}`
+	_, err := tool.ImportThoughts(context.Background(), nil, &mcp.CallToolParamsFor[ImportThoughtsInput]{Arguments: ImportThoughtsInput{Data: data}})
+	if err == nil || !strings.Contains(err.Error(), "thought #2") {
+		t.Fatalf("ImportThoughts with a tag outside of the allow-list returned %v, want an error for thought #2", err)
+	}
+	if len(tool.thoughts) != 0 {
+		t.Errorf("ImportThoughts appended %d thought(s) of a rejected batch", len(tool.thoughts))
+	}
+
+	data = `{"thoughts": [{"thought": "allowed", "tags": ["Plan"]}]}`
+	invoke(t, tool.ImportThoughts, ImportThoughtsInput{Data: data})
+	if len(tool.thoughts) != 1 || !slices.Equal(tool.thoughts[0].Tags, []string{"plan"}) {
+		t.Errorf("thoughts after importing = %+v, want the thought with its tag normalized", tool.thoughts)
+	}
+}
+
+func TestExportAndClearRequiresConfirmForLocked(t *testing.T) {
+	tool := NewThinkTool(Config{}, nil)
+	invoke(t, tool.Think, ThinkInput{Thought: "protected"})
+	invoke(t, tool.LockThought, LockThoughtInput{Index: 1})
+
+	_, err := tool.ExportCSV(context.Background(), nil, &mcp.CallToolParamsFor[ExportAndClearInput]{Arguments: ExportAndClearInput{AndClear: true}})
+	var te *ToolError
+	if !errors.As(err, &te) || te.Code != CodeLocked {
+		t.Fatalf("export_csv with and_clear of a locked thought returned %v, want a %s error", err, CodeLocked)
+	}
+	if len(tool.thoughts) != 1 {
+		t.Fatalf("a refused export_csv with and_clear left %d thought(s), want 1", len(tool.thoughts))
+	}
+
+	res := invoke(t, tool.ExportCSV, ExportAndClearInput{AndClear: true, Confirm: true})
+	if !strings.Contains(resultText(t, res), "protected") || len(tool.thoughts) != 0 {
+		t.Errorf("export_csv with and_clear and confirm exported %q and left %d thought(s), want the thought exported and cleared", resultText(t, res), len(tool.thoughts))
+	}
+}
+
+func TestExportPagesFitMaxBytes(t *testing.T) {
+	tool := NewThinkTool(Config{}, nil)
+	for i := range 30 {
+		invoke(t, tool.Think, ThinkInput{Thought: fmt.Sprintf("thought %d %s", i, strings.Repeat("x", i*7)), Tags: []string{"paging"}})
+	}
+	const maxBytes = 800
+	cursorPattern := regexp.MustCompile(`cursor "([^"]*)"`)
+
+	for _, format := range []string{"json", "markdown"} {
+		t.Run(format, func(t *testing.T) {
+			seen := []string{}
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(tool.thoughts) {
+					t.Fatal("the export does not end")
+				}
+				var text, next string
+				if format == "json" {
+					text = resultText(t, invoke(t, tool.ExportJSON, ExportJSONInput{MaxBytes: maxBytes, Cursor: cursor, Checksum: true}))
+					var doc exportDoc
+					if err := json.Unmarshal([]byte(text), &doc); err != nil {
+						t.Fatalf("page %d is not valid JSON: %v", pages+1, err)
+					}
+					for _, thought := range doc.Thoughts {
+						seen = append(seen, thought.ID)
+					}
+					next = doc.NextCursor
+				} else {
+					text = resultText(t, invoke(t, tool.ExportMarkdown, ExportInput{MaxBytes: maxBytes, Cursor: cursor}))
+					for _, thought := range tool.thoughts {
+						if strings.Contains(text, thought.Thought+"\n") {
+							seen = append(seen, thought.ID)
+						}
+					}
+					if m := cursorPattern.FindStringSubmatch(text); m != nil {
+						next = m[1]
+					}
+				}
+				if len(text) > maxBytes {
+					t.Errorf("page %d has %d bytes, more than max_bytes %d", pages+1, len(text), maxBytes)
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			want := []string{}
+			for _, thought := range tool.thoughts {
+				want = append(want, thought.ID)
+			}
+			if !slices.Equal(seen, want) {
+				t.Errorf("the pages exported %d thought(s), want each of the %d thoughts once in order", len(seen), len(want))
+			}
+		})
+	}
+}
+
+func TestExportCursorSurvivesRemovals(t *testing.T) {
+	tool := NewThinkTool(Config{}, nil)
+	for i := range 10 {
+		invoke(t, tool.Think, ThinkInput{Thought: fmt.Sprintf("thought %d %s", i, strings.Repeat("x", 100))})
+	}
+	var first exportDoc
+	json.Unmarshal([]byte(resultText(t, invoke(t, tool.ExportJSON, ExportJSONInput{MaxBytes: 600}))), &first)
+	if first.NextCursor == "" || len(first.Thoughts) == 0 {
+		t.Fatalf("the first page holds all %d thoughts, want several pages", len(first.Thoughts))
+	}
+
+	// Removing an exported thought must not make the next page skip one.
+	tool.rearrange([]int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	var second exportDoc
+	json.Unmarshal([]byte(resultText(t, invoke(t, tool.ExportJSON, ExportJSONInput{MaxBytes: 600, Cursor: first.NextCursor}))), &second)
+	if len(second.Thoughts) == 0 || second.Thoughts[0].ID != tool.thoughts[len(first.Thoughts)-1].ID {
+		t.Errorf("the second page after a removal does not start right after the first page")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type SetGoalInput struct {
+	Goal string `json:"goal" jsonschema:"what the reasoning is for, e.g. find the cause of the flaky login test; empty removes the goal"`
+}
+
+// SetGoal is a tool that sets the goal of the session, which is shown above
+// the thoughts returned by get_thoughts.
+func (t *ThinkTool) SetGoal(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SetGoalInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	goal := strings.TrimSpace(params.Arguments.Goal)
+	text := "Goal removed."
+	if goal != "" {
+		text = "Goal: " + goal
+	}
+	t.goal = goal
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text + warning}}}, nil
+}
+
+// GetGoal is a tool that returns the goal of the session.
+func (t *ThinkTool) GetGoal(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.goal == "" {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No goal set. Use set_goal to describe what the reasoning is for."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Goal: %s", t.goal)}},
+		StructuredContent: map[string]string{"goal": t.goal},
+	}, nil
+}
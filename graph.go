@@ -0,0 +1,198 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// checkDependencies validates the dependencies of the thought with the given
+// 1-based index, which may be one past the end of the log for a thought that
+// is about to be recorded. It returns the dependencies sorted and without
+// duplicates, or an error if one does not exist or would close a cycle. It
+// must be called with t.mu held.
+func (t *ThinkTool) checkDependencies(index int, deps []int) ([]int, error) {
+	if len(deps) == 0 {
+		return nil, nil
+	}
+	deps = slices.Compact(slices.Sorted(slices.Values(deps)))
+	for _, d := range deps {
+		if d == index {
+			return nil, toolError(CodeInvalidInput, "thought #%d cannot depend on itself", index)
+		}
+		if _, err := t.thoughtAt(d); err != nil {
+			return nil, toolError(CodeOutOfRange, "invalid dependency: %v", err)
+		}
+		if chain := t.dependencyPath(d, index); chain != nil {
+			cycle := []string{fmt.Sprintf("#%d", index)}
+			for _, c := range chain {
+				cycle = append(cycle, fmt.Sprintf("#%d", c))
+			}
+			return nil, toolError(CodeInvalidInput, "depending on #%d would create a cycle: %s", d, strings.Join(cycle, " -> "))
+		}
+	}
+	return deps, nil
+}
+
+// dependencyPath returns a chain of dependencies leading from thought from to
+// thought to, both 1-based and included, or nil if to cannot be reached. It
+// must be called with t.mu held.
+func (t *ThinkTool) dependencyPath(from, to int) []int {
+	visited := map[int]bool{}
+	var walk func(i int) []int
+	walk = func(i int) []int {
+		if i == to {
+			return []int{i}
+		}
+		if visited[i] || i < 1 || i > len(t.thoughts) {
+			return nil
+		}
+		visited[i] = true
+		for _, d := range t.thoughts[i-1].DependsOn {
+			if chain := walk(d); chain != nil {
+				return append([]int{i}, chain...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+type SetDependenciesInput struct {
+	Index     int   `json:"index" jsonschema:"the 1-based index of the thought whose dependencies to set"`
+	DependsOn []int `json:"depends_on" jsonschema:"1-based indices of the thoughts that must be resolved first; an empty list removes all dependencies"`
+}
+
+// SetDependencies is a tool that replaces the dependencies of a thought.
+func (t *ThinkTool) SetDependencies(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SetDependenciesInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	i, err := t.thoughtAt(args.Index)
+	if err != nil {
+		return nil, err
+	}
+	if t.thoughts[i].Locked {
+		return nil, errLocked(i)
+	}
+	deps, err := t.checkDependencies(i+1, args.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+
+	t.thoughts[i].DependsOn = deps
+	t.thoughts[i].UpdatedAt = t.clock.Now().Format(time.RFC3339)
+	warning := t.persist(sess)
+	if len(deps) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Removed the dependencies of thought #%d.%s", i+1, warning)}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Thought #%d now depends on %d thought(s).%s", i+1, len(deps), warning)}}}, nil
+}
+
+// GetReadyThoughts is a tool that returns the unresolved thoughts whose
+// dependencies are all resolved.
+func (t *ThinkTool) GetReadyThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	now := t.clock.Now()
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if thought.Resolved || thought.expired(now) {
+			continue
+		}
+		ready := true
+		for _, d := range thought.DependsOn {
+			// Dependencies that do not exist, e.g. in a hand-edited
+			// persistence file, do not block.
+			if d >= 1 && d <= len(t.thoughts) && !t.thoughts[d-1].Resolved {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			thoughts = append(thoughts, t.formatThought(i, thought))
+		}
+	}
+	if len(thoughts) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No thought is ready: every unresolved thought waits for an unresolved dependency."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
+}
+
+// mentionPattern matches a textual mention of a thought such as "see #3".
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w&#])#(\d+)\b`)
+
+// DanglingReference is a link of a thought to a thought that does not exist.
+type DanglingReference struct {
+	Index  int    `json:"index"` // 1-based index of the thought with the link
+	Kind   string `json:"kind"`  // parent, dependency or mention
+	Target int    `json:"target"`
+}
+
+type FindDanglingReferencesInput struct {
+	Mentions bool `json:"mentions,omitempty" jsonschema:"also check mentions such as 'see #3' in the text of the thoughts; note that #N may also refer to an issue"`
+}
+
+// FindDanglingReferences is a tool that reports the parent and dependency
+// links, and optionally the #N mentions in the text, that point to thoughts
+// that do not exist, e.g. after imports or edits of the stored log.
+func (t *ThinkTool) FindDanglingReferences(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[FindDanglingReferencesInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	missing := func(index int) bool { return index < 1 || index > len(t.thoughts) }
+
+	dangling := []DanglingReference{}
+	for i, thought := range t.thoughts {
+		if thought.ParentIndex != 0 && missing(thought.ParentIndex) {
+			dangling = append(dangling, DanglingReference{Index: i + 1, Kind: "parent", Target: thought.ParentIndex})
+		}
+		for _, d := range thought.DependsOn {
+			if missing(d) {
+				dangling = append(dangling, DanglingReference{Index: i + 1, Kind: "dependency", Target: d})
+			}
+		}
+		if !params.Arguments.Mentions {
+			continue
+		}
+		for _, m := range mentionPattern.FindAllStringSubmatch(thought.Thought, -1) {
+			if n, err := strconv.Atoi(m[1]); err != nil || missing(n) {
+				dangling = append(dangling, DanglingReference{Index: i + 1, Kind: "mention", Target: n})
+			}
+		}
+	}
+
+	if len(dangling) == 0 {
+		return &mcp.CallToolResultFor[any]{
+			Content:           []mcp.Content{&mcp.TextContent{Text: "No dangling references found."}},
+			StructuredContent: map[string]any{"dangling": dangling},
+		}, nil
+	}
+	lines := []string{fmt.Sprintf("%d dangling reference(s), valid indices are 1 to %d:", len(dangling), len(t.thoughts))}
+	for _, d := range dangling {
+		lines = append(lines, fmt.Sprintf("  Thought #%d: %s #%d does not exist", d.Index, d.Kind, d.Target))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"dangling": dangling},
+	}, nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// exportPath is the HTTP path under which a server in HTTP mode serves its
+// thoughts for peers to pull.
+const exportPath = "/export.json"
+
+// thoughtPath is the HTTP path under which a server in HTTP mode serves the
+// permalinks of single thoughts, followed by the thought ID.
+const thoughtPath = "/thoughts/"
+
+// serveHTTP serves the MCP server over streamable HTTP at /mcp, along with
+// the read-only export and permalink endpoints, until ctx is done.
+func (t *ThinkTool) serveHTTP(ctx context.Context, server *mcp.Server) error {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	mux.HandleFunc("GET "+exportPath, t.handleExport)
+	mux.HandleFunc("GET "+thoughtPath+"{id}", t.handleThought)
+
+	srv := &http.Server{Addr: t.cfg.Addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleExport serves all thoughts as a checksummed JSON export.
+func (t *ThinkTool) handleExport(w http.ResponseWriter, r *http.Request) {
+	t.pruneExpired()
+	t.mu.RLock()
+	doc := exportDoc{Thoughts: cloneThoughts(t.thoughts)}
+	t.mu.RUnlock()
+
+	sum, err := checksum(doc.Thoughts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc.Checksum = sum
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		slog.Error("failed to write export", slog.Any("error", err))
+	}
+}
+
+// handleThought serves the thought with the ID of the permalink as plain
+// text, formatted as in get_thoughts.
+func (t *ThinkTool) handleThought(w http.ResponseWriter, r *http.Request) {
+	t.pruneExpired()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	id := r.PathValue("id")
+	for i, thought := range t.thoughts {
+		if thought.ID == id {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, t.formatThought(i, thought))
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("thought %q not found", id), http.StatusNotFound)
+}
+
+// baseURL returns the URL under which clients reach the server in HTTP mode,
+// or nil in stdio mode.
+func (t *ThinkTool) baseURL() (*url.URL, error) {
+	if t.cfg.Transport != "http" {
+		return nil, nil
+	}
+	base := t.cfg.BaseURL
+	if base == "" {
+		host := t.cfg.Addr
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		base = "http://" + host
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q, expected an http(s) URL", base)
+	}
+	return u, nil
+}
+
+// ThoughtLink is the permalink of a thought.
+type ThoughtLink struct {
+	Index int    `json:"index"`
+	ID    string `json:"id"`
+	URL   string `json:"url"`
+}
+
+// GetThoughtsWithLinks is a tool that returns all thoughts like get_thoughts
+// along with a permalink to each, which stays valid while the thought exists
+// as it is based on the thought ID. Permalinks are only available in HTTP
+// mode.
+func (t *ThinkTool) GetThoughtsWithLinks(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	base, err := t.baseURL()
+	if err != nil {
+		return nil, toolError(CodeInternal, "%v", err)
+	}
+
+	parts := []string{}
+	links := []ThoughtLink{}
+	if base == nil {
+		parts = append(parts, "Note: permalinks are unavailable since the server does not run in HTTP mode.\n")
+	}
+	for i, thought := range t.thoughts {
+		text := t.formatThought(i, thought)
+		if base != nil {
+			link := ThoughtLink{Index: i + 1, ID: thought.ID, URL: base.JoinPath(thoughtPath, thought.ID).String()}
+			links = append(links, link)
+			text = strings.TrimSuffix(text, "\n") + "\n  Link: " + link.URL + "\n"
+		}
+		parts = append(parts, text)
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           t.frames(parts, "\n"),
+		StructuredContent: map[string]any{"links": links},
+	}, nil
+}
+
+type PullFromPeerInput struct {
+	URL string `json:"url" jsonschema:"the base URL of a peer think-tool running in HTTP mode, e.g. http://localhost:8080"`
+}
+
+// PullFromPeer is a tool that appends the thoughts of another think-tool
+// instance to the log. Thoughts whose ID is already present are skipped, the
+// others are checked like imported ones.
+func (t *ThinkTool) PullFromPeer(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[PullFromPeerInput]) (*mcp.CallToolResultFor[any], error) {
+	base, err := url.Parse(strings.TrimSpace(params.Arguments.URL))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, toolError(CodeInvalidInput, "invalid peer URL %q, expected an http(s) base URL", params.Arguments.URL)
+	}
+
+	// Fetch without holding the lock, the peer may be slow.
+	doc, err := fetchExport(ctx, base.JoinPath(exportPath).String())
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := map[string]bool{}
+	for _, thought := range t.thoughts {
+		ids[thought.ID] = true
+	}
+	// Keep the parent and dependency links between the pulled thoughts that
+	// remain after skipping the ones already present.
+	renumbered := map[int]int{}
+	batch := []ThoughtItem{}
+	for i, thought := range doc.Thoughts {
+		if thought.ID != "" && ids[thought.ID] {
+			continue
+		}
+		if err := t.checkImported(thought); err != nil {
+			return nil, toolError(CodeInvalidInput, "thought #%d of the peer export is rejected: %v", i+1, err)
+		}
+		renumbered[i+1] = len(batch) + 1
+		batch = append(batch, thought)
+	}
+	for i := range batch {
+		batch[i] = batch[i].clone()
+		batch[i].relink(func(index int) int { return renumbered[index] })
+	}
+
+	n := t.appendImported(batch)
+	warning := ""
+	if n > 0 {
+		warning = t.persist(sess)
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Pulled %d new thought(s) from %s, skipped %d already present.%s", n, base, len(doc.Thoughts)-n, warning)}}}, nil
+}
+
+// fetchExport downloads and validates the export of a peer.
+func fetchExport(ctx context.Context, u string) (*exportDoc, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, toolError(CodeInvalidInput, "invalid peer URL: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, toolError(CodeNotFound, "peer unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, toolError(CodeNotFound, "peer returned %s for %s; is it running with -transport http?", resp.Status, u)
+	}
+
+	var doc exportDoc
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 64<<20))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, toolError(CodeInvalidInput, "peer sent an unexpected export format: %v", err)
+	}
+	if doc.Checksum != "" {
+		sum, err := checksum(doc.Thoughts)
+		if err != nil || sum != doc.Checksum {
+			return nil, toolError(CodeInvalidInput, "peer export failed checksum verification")
+		}
+	}
+	for i, thought := range doc.Thoughts {
+		if strings.TrimSpace(thought.Thought) == "" {
+			return nil, toolError(CodeInvalidInput, "thought #%d of the peer export has no text", i+1)
+		}
+	}
+	return &doc, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// messages is the catalog of localized response strings, keyed by locale and
+// message key. Only the framing is translated, never the thoughts themselves.
+// English is the fallback for unknown locales and missing keys.
+var messages = map[string]map[string]string{
+	"en": {
+		"thought.header":      "Thought #%d at %s",
+		"thought.recorded":    "Thought: %s",
+		"thought.paused":      "Recording is paused, the thought was not stored: %s. Use resume_recording to record thoughts again.",
+		"thoughts.cleared":    "Thoughts cleared.",
+		"error.empty_thought": "no thoughts provided",
+		"error.no_thoughts":   "no thoughts recorded. Use the think tool to record a thought first.",
+	},
+	"de": {
+		"thought.header":      "Gedanke #%d um %s",
+		"thought.recorded":    "Gedanke: %s",
+		"thought.paused":      "Die Aufzeichnung ist pausiert, der Gedanke wurde nicht gespeichert: %s. Verwende resume_recording, um wieder Gedanken aufzuzeichnen.",
+		"thoughts.cleared":    "Gedanken gelöscht.",
+		"error.empty_thought": "kein Gedanke angegeben",
+		"error.no_thoughts":   "keine Gedanken aufgezeichnet. Verwende das think-Werkzeug, um zuerst einen Gedanken aufzuzeichnen.",
+	},
+	"fr": {
+		"thought.header":      "Pensée n°%d à %s",
+		"thought.recorded":    "Pensée : %s",
+		"thought.paused":      "L'enregistrement est en pause, la pensée n'a pas été conservée : %s. Utilisez resume_recording pour enregistrer à nouveau des pensées.",
+		"thoughts.cleared":    "Pensées effacées.",
+		"error.empty_thought": "aucune pensée fournie",
+		"error.no_thoughts":   "aucune pensée enregistrée. Utilisez d'abord l'outil think pour enregistrer une pensée.",
+	},
+	"es": {
+		"thought.header":      "Pensamiento #%d a las %s",
+		"thought.recorded":    "Pensamiento: %s",
+		"thought.paused":      "La grabación está en pausa, el pensamiento no se guardó: %s. Usa resume_recording para volver a registrar pensamientos.",
+		"thoughts.cleared":    "Pensamientos borrados.",
+		"error.empty_thought": "no se proporcionó ningún pensamiento",
+		"error.no_thoughts":   "no hay pensamientos registrados. Usa primero la herramienta think para registrar un pensamiento.",
+	},
+	"zh": {
+		"thought.header":      "想法 #%d（%s）",
+		"thought.recorded":    "想法：%s",
+		"thought.paused":      "记录已暂停，该想法未被保存：%s。请使用 resume_recording 恢复记录。",
+		"thoughts.cleared":    "想法已清除。",
+		"error.empty_thought": "未提供想法",
+		"error.no_thoughts":   "尚未记录任何想法。请先使用 think 工具记录一个想法。",
+	},
+	"ja": {
+		"thought.header":      "思考 #%d（%s）",
+		"thought.recorded":    "思考：%s",
+		"thought.paused":      "記録は一時停止中のため、思考は保存されませんでした：%s。resume_recording で記録を再開してください。",
+		"thoughts.cleared":    "思考を消去しました。",
+		"error.empty_thought": "思考が指定されていません",
+		"error.no_thoughts":   "思考が記録されていません。まず think ツールで思考を記録してください。",
+	},
+}
+
+// catalog returns the messages for a locale such as "de" or "de-AT". A
+// regional locale falls back to its language, anything else to English.
+func catalog(locale string) map[string]string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if m, ok := messages[locale]; ok {
+		return m
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		if m, ok := messages[lang]; ok {
+			return m
+		}
+	}
+	return messages["en"]
+}
+
+// msg returns the localized message for key formatted with args.
+func (t *ThinkTool) msg(key string, args ...any) string {
+	format, ok := catalog(t.cfg.Locale)[key]
+	if !ok {
+		format = messages["en"][key]
+	}
+	return fmt.Sprintf(format, args...)
+}
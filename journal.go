@@ -0,0 +1,251 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// journalEvent is a line of the journal.
+type journalEvent struct {
+	Op      string       `json:"op"` // add, update, delete, clear or order
+	Time    string       `json:"time"`
+	Thought *ThoughtItem `json:"thought,omitempty"` // For add and update
+	ID      string       `json:"id,omitempty"`      // For delete
+	IDs     []string     `json:"ids,omitempty"`     // For order, the IDs of all thoughts in log order
+}
+
+// journalEvents returns the events that turn the log from the thoughts in prev
+// into the thoughts in curr. Thoughts are matched by ID.
+func journalEvents(prev, curr []ThoughtItem, now string) []journalEvent {
+	if len(curr) == 0 {
+		if len(prev) == 0 {
+			return nil
+		}
+		return []journalEvent{{Op: "clear", Time: now}}
+	}
+
+	before := map[string]ThoughtItem{}
+	for _, item := range prev {
+		before[item.ID] = item
+	}
+	after := map[string]bool{}
+	for _, item := range curr {
+		after[item.ID] = true
+	}
+
+	events := []journalEvent{}
+	order := []string{} // IDs in the order replaying the events would produce
+	for _, item := range prev {
+		if after[item.ID] {
+			order = append(order, item.ID)
+		} else {
+			events = append(events, journalEvent{Op: "delete", Time: now, ID: item.ID})
+		}
+	}
+	for _, item := range curr {
+		old, ok := before[item.ID]
+		switch {
+		case !ok:
+			events = append(events, journalEvent{Op: "add", Time: now, Thought: &item})
+			order = append(order, item.ID)
+		case !reflect.DeepEqual(old, item):
+			events = append(events, journalEvent{Op: "update", Time: now, Thought: &item})
+		}
+	}
+	ids := []string{}
+	for _, item := range curr {
+		ids = append(ids, item.ID)
+	}
+	if !slices.Equal(order, ids) {
+		events = append(events, journalEvent{Op: "order", Time: now, IDs: ids})
+	}
+	return events
+}
+
+// syncJournal appends the changes of the log since the last sync to the
+// journal. Scratch thoughts are left out. It must be called with t.mu held.
+func (t *ThinkTool) syncJournal() error {
+	if t.cfg.Journal == "" {
+		return nil
+	}
+	thoughts := durable(t.thoughts)
+	events := journalEvents(t.journaled, thoughts, t.clock.Now().Format(time.RFC3339))
+	if len(events) == 0 {
+		return nil
+	}
+
+	var b []byte
+	for _, ev := range events {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal event: %w", err)
+		}
+		b = append(append(b, line...), '\n')
+	}
+	f, err := os.OpenFile(t.cfg.Journal, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	t.journaled = cloneThoughts(thoughts)
+	return nil
+}
+
+// seedJournal brings the journal in line with the thoughts loaded from the
+// persistence file. A journal that is missing or empty, e.g. because -journal
+// was just enabled for an existing store, gets an event for every thought so
+// that rebuild_from_journal can restore them. An existing journal is assumed
+// to be in sync already.
+func (t *ThinkTool) seedJournal() error {
+	if t.cfg.Journal == "" {
+		return nil
+	}
+	info, err := os.Stat(t.cfg.Journal)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	if err == nil && info.Size() > 0 {
+		t.journaled = cloneThoughts(t.thoughts)
+		return nil
+	}
+	t.journaled = nil
+	if err := t.syncJournal(); err != nil {
+		return fmt.Errorf("failed to seed journal %s: %w", t.cfg.Journal, err)
+	}
+	return nil
+}
+
+// replayJournal applies the events of a journal in order and returns the
+// resulting thoughts, the number of events applied and the line numbers of
+// the lines that could not be decoded.
+func replayJournal(r io.Reader) (thoughts []ThoughtItem, applied int, skipped []int, err error) {
+	thoughts = []ThoughtItem{}
+	find := func(id string) int {
+		return slices.IndexFunc(thoughts, func(item ThoughtItem) bool { return item.ID == id })
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var ev journalEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			skipped = append(skipped, line)
+			continue
+		}
+		switch ev.Op {
+		case "add", "update":
+			if ev.Thought == nil {
+				skipped = append(skipped, line)
+				continue
+			}
+			if i := find(ev.Thought.ID); i >= 0 {
+				thoughts[i] = *ev.Thought
+			} else {
+				thoughts = append(thoughts, *ev.Thought)
+			}
+		case "delete":
+			if i := find(ev.ID); i >= 0 {
+				thoughts = slices.Delete(thoughts, i, i+1)
+			}
+		case "clear":
+			thoughts = []ThoughtItem{}
+		case "order":
+			ordered := []ThoughtItem{}
+			for _, id := range ev.IDs {
+				if i := find(id); i >= 0 {
+					ordered = append(ordered, thoughts[i])
+				}
+			}
+			for _, item := range thoughts {
+				if !slices.Contains(ev.IDs, item.ID) {
+					ordered = append(ordered, item)
+				}
+			}
+			thoughts = ordered
+		default:
+			skipped = append(skipped, line)
+			continue
+		}
+		applied++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+	return thoughts, applied, skipped, nil
+}
+
+type RebuildFromJournalInput struct {
+	Confirm bool `json:"confirm,omitempty" jsonschema:"confirm replacing the log even though it contains locked thoughts"`
+}
+
+// RebuildFromJournal is a tool that replaces the log with the thoughts
+// reconstructed from the journal, e.g. after the persistence file was lost.
+// Lines that cannot be decoded, such as a line cut short by a crash, are
+// skipped. Like ClearThoughts, it refuses to drop locked thoughts unless
+// confirmed.
+func (t *ThinkTool) RebuildFromJournal(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[RebuildFromJournalInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cfg.Journal == "" {
+		return nil, toolError(CodeNotFound, "the journal is disabled. Start the server with -journal to keep one.")
+	}
+	if err := t.checkClear(params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(t.cfg.Journal)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, toolError(CodeNotFound, "the journal %s does not exist yet", t.cfg.Journal)
+	}
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	thoughts, applied, skipped, err := replayJournal(f)
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to read journal: %v", err)
+	}
+
+	t.thoughts = thoughts
+	t.journaled = cloneThoughts(thoughts)
+	t.lastCleared = nil
+	warning := t.persist(sess)
+
+	text := fmt.Sprintf("Rebuilt %d thought(s) from %d journal event(s).", len(thoughts), applied)
+	if len(skipped) > 0 {
+		slog.Warn("skipped corrupt journal lines", slog.String("path", t.cfg.Journal), slog.Any("lines", skipped))
+		lines := []string{}
+		for _, line := range skipped {
+			lines = append(lines, fmt.Sprint(line))
+		}
+		text += fmt.Sprintf("\nWarning: skipped %d corrupt line(s): %s.", len(skipped), strings.Join(lines, ", "))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text + warning}}}, nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// latencySamples is the number of most recent durations kept per tool.
+const latencySamples = 1024
+
+// latencies records tool call durations in a ring buffer per tool. It has its
+// own mutex so that recording never waits for the thought log.
+type latencies struct {
+	mu      sync.Mutex
+	samples map[string]*latencyRing
+}
+
+type latencyRing struct {
+	durations []time.Duration
+	next      int   // Position of the next sample once the ring is full
+	count     int64 // Total number of calls, including evicted samples
+}
+
+func (l *latencies) record(tool string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.samples == nil {
+		l.samples = map[string]*latencyRing{}
+	}
+	r, ok := l.samples[tool]
+	if !ok {
+		r = &latencyRing{}
+		l.samples[tool] = r
+	}
+	r.count++
+	if len(r.durations) < latencySamples {
+		r.durations = append(r.durations, d)
+		return
+	}
+	r.durations[r.next] = d
+	r.next = (r.next + 1) % latencySamples
+}
+
+// recordLatency is a server middleware that records the duration of every
+// tool call.
+func (t *ThinkTool) recordLatency(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, sess *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		p, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok {
+			return next(ctx, sess, method, params)
+		}
+		start := time.Now()
+		defer func() { t.latencies.record(p.Name, time.Since(start)) }()
+		return next(ctx, sess, method, params)
+	}
+}
+
+// ToolLatency summarizes the recorded durations of a tool.
+type ToolLatency struct {
+	Tool  string  `json:"tool"`
+	Count int64   `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// percentile returns the p-th percentile of sorted durations using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+// LatencyReport is a tool that reports the latency percentiles of the tool
+// calls served so far. Percentiles cover the most recent calls of each tool.
+func (t *ThinkTool) LatencyReport(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.latencies.mu.Lock()
+	report := []ToolLatency{}
+	for tool, r := range t.latencies.samples {
+		sorted := slices.Clone(r.durations)
+		slices.Sort(sorted)
+		report = append(report, ToolLatency{
+			Tool:  tool,
+			Count: r.count,
+			P50Ms: ms(percentile(sorted, 0.5)),
+			P95Ms: ms(percentile(sorted, 0.95)),
+			MaxMs: ms(sorted[len(sorted)-1]),
+		})
+	}
+	t.latencies.mu.Unlock()
+
+	if len(report) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No tool calls recorded yet."}}}, nil
+	}
+	slices.SortFunc(report, func(a, b ToolLatency) int { return strings.Compare(a.Tool, b.Tool) })
+
+	lines := []string{}
+	for _, l := range report {
+		lines = append(lines, fmt.Sprintf("%s: %d call(s), p50 %.2fms, p95 %.2fms, max %.2fms", l.Tool, l.Count, l.P50Ms, l.P95Ms, l.MaxMs))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: map[string]any{"tools": report},
+	}, nil
+}
+
+type ThroughputReportInput struct {
+	Minutes int `json:"minutes,omitempty" jsonschema:"length of the recent window in minutes, defaults to 5"`
+}
+
+// Throughput is the recording rate of thoughts.
+type Throughput struct {
+	Thoughts        int     `json:"thoughts"`
+	SessionMinutes  float64 `json:"session_minutes"`
+	PerMinute       float64 `json:"per_minute"`
+	WindowMinutes   int     `json:"window_minutes"`
+	WindowThoughts  int     `json:"window_thoughts"`
+	WindowPerMinute float64 `json:"window_per_minute"`
+	PeakPerMinute   int     `json:"peak_per_minute"`
+}
+
+// ThroughputReport is a tool that reports how many thoughts are recorded per
+// minute, over the whole session and within the last minutes. The session
+// starts with the earliest recorded thought; spans shorter than a minute count
+// as one minute so that a handful of quick thoughts does not report an
+// inflated rate. The peak is the largest number of thoughts recorded within a
+// single minute, which tells bursty from steady reasoning.
+func (t *ThinkTool) ThroughputReport(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThroughputReportInput]) (*mcp.CallToolResultFor[any], error) {
+	window := params.Arguments.Minutes
+	if window < 0 {
+		return nil, toolError(CodeOutOfRange, "minutes must not be negative, got %d", window)
+	}
+	if window == 0 {
+		window = 5
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	now := t.clock.Now()
+	since := now.Add(-time.Duration(window) * time.Minute)
+	start := now
+	perMinute := map[int64]int{}
+	r := Throughput{Thoughts: len(t.thoughts), WindowMinutes: window}
+	for i, thought := range t.thoughts {
+		createdAt, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		if createdAt.Before(start) {
+			start = createdAt
+		}
+		if !createdAt.Before(since) {
+			r.WindowThoughts++
+		}
+		minute := createdAt.Unix() / 60
+		perMinute[minute]++
+		r.PeakPerMinute = max(r.PeakPerMinute, perMinute[minute])
+	}
+	session := max(now.Sub(start), time.Minute)
+	r.SessionMinutes = session.Minutes()
+	r.PerMinute = float64(r.Thoughts) / r.SessionMinutes
+	r.WindowPerMinute = float64(r.WindowThoughts) / min(session, time.Duration(window)*time.Minute).Minutes()
+
+	text := fmt.Sprintf("%d thought(s) in %.1f minute(s): %.2f per minute.\nLast %d minute(s): %d thought(s), %.2f per minute.\nPeak: %d thought(s) within one minute.",
+		r.Thoughts, r.SessionMinutes, r.PerMinute, window, r.WindowThoughts, r.WindowPerMinute, r.PeakPerMinute)
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: text}},
+		StructuredContent: r,
+	}, nil
+}
+
+type ActivityHistogramInput struct {
+	Bucket string `json:"bucket" jsonschema:"the width of a bucket as a duration, e.g. 1h or 15m"`
+}
+
+// ActivityBucket is the number of thoughts recorded in a bucket of the
+// activity histogram.
+type ActivityBucket struct {
+	Start    string `json:"start"`
+	Thoughts int    `json:"thoughts"`
+}
+
+// maxActivityBuckets limits the size of the histogram.
+const maxActivityBuckets = 1000
+
+// sparkBars are the bars of a sparkline, from lowest to highest.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// ActivityHistogram is a tool that counts the thoughts recorded per time
+// bucket, from the bucket of the earliest thought to that of the latest.
+// Buckets are aligned to multiples of their width since the zero time, so
+// that 1h buckets start at the full hour. Buckets without thoughts are
+// included with a count of zero.
+func (t *ThinkTool) ActivityHistogram(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ActivityHistogramInput]) (*mcp.CallToolResultFor[any], error) {
+	bucket, err := time.ParseDuration(params.Arguments.Bucket)
+	if err != nil || bucket <= 0 {
+		return nil, toolError(CodeInvalidInput, "invalid bucket %q, expected a positive duration such as 1h", params.Arguments.Bucket)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	starts := []time.Time{}
+	for i, thought := range t.thoughts {
+		createdAt, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		starts = append(starts, createdAt.Truncate(bucket))
+	}
+	first, last := slices.MinFunc(starts, time.Time.Compare), slices.MaxFunc(starts, time.Time.Compare)
+	n := int(last.Sub(first)/bucket) + 1
+	if n > maxActivityBuckets {
+		return nil, toolError(CodeOutOfRange, "a bucket of %s splits the log into %d buckets, at most %d are supported. Choose a wider bucket.", bucket, n, maxActivityBuckets)
+	}
+
+	buckets := make([]ActivityBucket, n)
+	for i := range buckets {
+		buckets[i].Start = first.Add(time.Duration(i) * bucket).Format(time.RFC3339)
+	}
+	peak := 0
+	for _, start := range starts {
+		b := &buckets[int(start.Sub(first)/bucket)]
+		b.Thoughts++
+		peak = max(peak, b.Thoughts)
+	}
+
+	spark := []rune{}
+	lines := []string{}
+	for _, b := range buckets {
+		bar := ' '
+		if b.Thoughts > 0 {
+			bar = sparkBars[(b.Thoughts*len(sparkBars)-1)/peak]
+		}
+		spark = append(spark, bar)
+		lines = append(lines, fmt.Sprintf("%s: %d", b.Start, b.Thoughts))
+	}
+	text := fmt.Sprintf("%s\n%s to %s, %s per bucket, peak %d thought(s)\n\n%s",
+		string(spark), buckets[0].Start, buckets[n-1].Start, bucket, peak, strings.Join(lines, "\n"))
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: text}},
+		StructuredContent: map[string]any{"bucket": bucket.String(), "buckets": buckets},
+	}, nil
+}
+
+// RuntimeStatsResult is the structured result of the runtime_stats tool.
+type RuntimeStatsResult struct {
+	Goroutines      int     `json:"goroutines"`
+	HeapAllocBytes  uint64  `json:"heap_alloc_bytes"`
+	HeapObjects     uint64  `json:"heap_objects"`
+	SysBytes        uint64  `json:"sys_bytes"` // Memory obtained from the OS
+	NumGC           uint32  `json:"num_gc"`
+	UptimeSeconds   float64 `json:"uptime_seconds"`
+	Thoughts        int     `json:"thoughts"`
+	Sessions        int     `json:"sessions"`         // Connected sessions
+	TrackedSessions int     `json:"tracked_sessions"` // Sessions the server keeps state for, dropped when they end
+	Snapshots       int     `json:"snapshots"`
+	Shares          int     `json:"shares"`
+}
+
+// RuntimeStats is a tool that reports the resource use of the server along
+// with the sizes of its state, e.g. to spot leaks in long-running servers. It
+// does not change anything.
+func (t *ThinkTool) RuntimeStats(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := RuntimeStatsResult{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		UptimeSeconds:  t.clock.Now().Sub(t.started).Seconds(),
+	}
+	if t.server != nil {
+		for range t.server.Sessions() {
+			stats.Sessions++
+		}
+	}
+
+	t.mu.RLock()
+	stats.Thoughts = len(t.thoughts)
+	stats.TrackedSessions = len(t.sessions)
+	stats.Snapshots = len(t.snapshots)
+	stats.Shares = len(t.shares)
+	t.mu.RUnlock()
+
+	lines := []string{
+		fmt.Sprintf("Uptime: %s", time.Duration(stats.UptimeSeconds*float64(time.Second)).Round(time.Second)),
+		fmt.Sprintf("Goroutines: %d", stats.Goroutines),
+		fmt.Sprintf("Heap: %.1f MiB in %d objects, %.1f MiB obtained from the OS, %d GC cycles", mib(stats.HeapAllocBytes), stats.HeapObjects, mib(stats.SysBytes), stats.NumGC),
+		fmt.Sprintf("Thoughts: %d", stats.Thoughts),
+		fmt.Sprintf("Sessions: %d connected, %d tracked", stats.Sessions, stats.TrackedSessions),
+		fmt.Sprintf("Snapshots: %d, shares: %d", stats.Snapshots, stats.Shares),
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: stats,
+	}, nil
+}
+
+func mib(b uint64) float64 { return float64(b) / (1 << 20) }
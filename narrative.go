@@ -0,0 +1,113 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Default transition phrases of get_narrative.
+var (
+	defaultNarrativeFirst       = "First,"
+	defaultNarrativeTransitions = []string{"Then,", "Next,", "After that,", "Building on that,"}
+	defaultNarrativeLast        = "Finally,"
+)
+
+// narrativeSentence turns the text of a thought into a sentence following
+// the given transition phrase: whitespace is collapsed into single spaces, a
+// capitalized first word that is not an acronym or "I" is lowercased and a
+// final period is added if the text does not end with punctuation.
+func narrativeSentence(transition, text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if transition != "" {
+		word, _, _ := strings.Cut(text, " ")
+		first, n := utf8.DecodeRuneInString(word)
+		second, _ := utf8.DecodeRuneInString(word[n:])
+		if word != "I" && !strings.HasPrefix(word, "I'") && unicode.IsUpper(first) && (n == len(word) || unicode.IsLower(second)) {
+			text = string(unicode.ToLower(first)) + text[n:]
+		}
+		text = transition + " " + text
+	}
+	if last, _ := utf8.DecodeLastRuneInString(text); !unicode.IsPunct(last) {
+		text += "."
+	}
+	return text
+}
+
+type GetNarrativeInput struct {
+	First       string   `json:"first,omitempty" jsonschema:"phrase introducing the first thought, defaults to 'First,'"`
+	Transitions []string `json:"transitions,omitempty" jsonschema:"phrases introducing the thoughts in between, used in turn, defaults to 'Then,', 'Next,', 'After that,' and 'Building on that,'"`
+	Last        string   `json:"last,omitempty" jsonschema:"phrase introducing the last thought, defaults to 'Finally,'"`
+}
+
+// GetNarrative is a tool that returns the log as a single paragraph of prose.
+// The thoughts are ordered by creation time, with ties in log order, and each
+// becomes a sentence introduced by a transition phrase: the first phrase for
+// the first thought, the last phrase for the last one and the transitions in
+// turn for the thoughts in between. A single thought is returned without a
+// phrase. The result only depends on the log and the phrases.
+func (t *ThinkTool) GetNarrative(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetNarrativeInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	args := params.Arguments
+	first, last := strings.TrimSpace(args.First), strings.TrimSpace(args.Last)
+	if first == "" {
+		first = defaultNarrativeFirst
+	}
+	if last == "" {
+		last = defaultNarrativeLast
+	}
+	transitions := []string{}
+	for _, phrase := range args.Transitions {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			transitions = append(transitions, phrase)
+		}
+	}
+	if len(transitions) == 0 {
+		transitions = defaultNarrativeTransitions
+	}
+
+	created := make([]time.Time, len(t.thoughts))
+	order := make([]int, len(t.thoughts))
+	for i, thought := range t.thoughts {
+		at, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		created[i], order[i] = at, i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return created[a].Compare(created[b]) })
+
+	sentences := []string{}
+	for n, i := range order {
+		transition := ""
+		switch {
+		case len(order) == 1:
+		case n == 0:
+			transition = first
+		case n == len(order)-1:
+			transition = last
+		default:
+			transition = transitions[(n-1)%len(transitions)]
+		}
+		sentences = append(sentences, narrativeSentence(transition, t.thoughts[i].Thought))
+	}
+	text := strings.Join(sentences, " ")
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: text}},
+		StructuredContent: map[string]any{"narrative": text, "thoughts": len(order)},
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// keyedSecret matches secrets written as key=value or key: value, of which
+// only the value is redacted.
+var keyedSecret = regexp.MustCompile(`(?i)\b(api[_-]?key|secret|password|passwd|token)(\s*[:=]\s*)[^\s\[]\S*`)
+
+// defaultRedactions match common secrets and personal data.
+var defaultRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),                           // AWS access key ID
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),                 // GitHub token
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}\b`),                      // OpenAI and Anthropic style API key
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),               // Slack token
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), // Email address
+}
+
+// patternList is a flag.Value collecting regular expressions, one per use of
+// the flag.
+type patternList []string
+
+func (p *patternList) String() string { return strings.Join(*p, " ") }
+
+func (p *patternList) Set(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return err
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// redact replaces the secrets in text with [REDACTED].
+func (t *ThinkTool) redact(text string) string {
+	text = keyedSecret.ReplaceAllString(text, "${1}${2}[REDACTED]")
+	for _, re := range t.redactions {
+		text = re.ReplaceAllString(text, "[REDACTED]")
+	}
+	return text
+}
+
+// GetRedactedThoughts is a tool that returns the thoughts recorded so far with
+// secrets such as API keys, tokens, private keys and email addresses replaced
+// by [REDACTED]. Further patterns can be added with -redact. The stored
+// thoughts are not modified.
+func (t *ThinkTool) GetRedactedThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		item := thought.clone()
+		item.Thought = t.redact(item.Thought)
+		for j, note := range item.Notes {
+			item.Notes[j] = t.redact(note)
+		}
+		for j, ref := range item.References {
+			item.References[j] = t.redact(ref)
+		}
+		thoughts = append(thoughts, t.formatThought(i, item))
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n")}, nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// tfidfIndex is the TF-IDF weighted term vector of every thought of the log,
+// computed over the log itself as the corpus.
+type tfidfIndex struct {
+	idf     map[string]float64   // Inverse document frequency by term
+	vectors []map[string]float64 // TF-IDF vector of each thought
+}
+
+// newTFIDFIndex indexes the given thoughts, ignoring the default stopwords.
+// The inverse document frequency of a term is smoothed to ln((1+n)/(1+df))+1
+// so that a term in every thought still counts a little.
+func newTFIDFIndex(thoughts []ThoughtItem) *tfidfIndex {
+	stopwords := stopwordSet(nil)
+	terms := make([]map[string]float64, len(thoughts))
+	df := map[string]int{}
+	for i, thought := range thoughts {
+		terms[i] = termVector(thought.Thought, stopwords)
+		for w := range terms[i] {
+			df[w]++
+		}
+	}
+
+	idx := &tfidfIndex{idf: make(map[string]float64, len(df)), vectors: terms}
+	n := float64(len(thoughts))
+	for w, d := range df {
+		idx.idf[w] = math.Log((1+n)/(1+float64(d))) + 1
+	}
+	for _, v := range idx.vectors {
+		for w := range v {
+			v[w] *= idx.idf[w]
+		}
+	}
+	return idx
+}
+
+// query returns the TF-IDF vector of text. Terms that occur in no thought are
+// left out since they cannot match anything.
+func (idx *tfidfIndex) query(text string) map[string]float64 {
+	v := termVector(text, stopwordSet(nil))
+	for w := range v {
+		if idf, ok := idx.idf[w]; ok {
+			v[w] *= idf
+		} else {
+			delete(v, w)
+		}
+	}
+	return v
+}
+
+// relevanceIndex returns the TF-IDF index of the log, building it on first
+// use after a change. It must be called with t.mu held, for reading or
+// writing.
+func (t *ThinkTool) relevanceIndex() *tfidfIndex {
+	// Readers share t.mu, so building the index needs a lock of its own.
+	t.relevanceMu.Lock()
+	defer t.relevanceMu.Unlock()
+	if t.relevance == nil {
+		t.relevance = newTFIDFIndex(t.thoughts)
+	}
+	return t.relevance
+}
+
+// relevant returns up to limit thoughts with a positive relevance to the
+// query vector, most relevant first, leaving out the thought at position
+// skip. It must be called with t.mu held.
+func (t *ThinkTool) relevant(idx *tfidfIndex, query map[string]float64, skip, limit int) []RelevantThought {
+	matches := []RelevantThought{}
+	for i, v := range idx.vectors {
+		if i == skip {
+			continue
+		}
+		if score := cosine(query, v); score > 0 {
+			matches = append(matches, RelevantThought{Index: i + 1, Score: score, Thought: t.thoughts[i].Thought})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b RelevantThought) int { return cmp.Compare(b.Score, a.Score) })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// formatRelevant lists thoughts with their relevance scores.
+func (t *ThinkTool) formatRelevant(matches []RelevantThought) string {
+	results := []string{}
+	for _, m := range matches {
+		results = append(results, fmt.Sprintf("Thought #%d (score %.2f) at %s:\n%s\n", m.Index, m.Score, t.thoughts[m.Index-1].CreatedAt, m.Thought))
+	}
+	return strings.Join(results, "\n")
+}
+
+type GetRelevantThoughtsInput struct {
+	Context string `json:"context" jsonschema:"the text to find relevant thoughts for, e.g. the current question or subtask"`
+	Limit   int    `json:"limit,omitempty" jsonschema:"maximum number of results, defaults to 5"`
+}
+
+// RelevantThought is a thought with its relevance to the given context.
+type RelevantThought struct {
+	Index   int     `json:"index"`
+	Score   float64 `json:"score"`
+	Thought string  `json:"thought"`
+}
+
+// GetRelevantThoughts is a tool that returns the thoughts most relevant to a
+// context, ranked by the cosine similarity of their TF-IDF vectors. Unlike
+// fuzzy_search_thoughts, words are weighted by how rare they are in the log,
+// so that distinctive words decide the ranking. Thoughts sharing no word with
+// the context are left out. The index is cached until the log changes.
+func (t *ThinkTool) GetRelevantThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetRelevantThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	if strings.TrimSpace(args.Context) == "" {
+		return nil, toolError(CodeEmptyInput, "no context provided")
+	}
+	if args.Limit < 0 {
+		return nil, toolError(CodeOutOfRange, "limit must not be negative")
+	}
+	limit := args.Limit
+	if limit == 0 {
+		limit = 5
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	idx := t.relevanceIndex()
+	matches := t.relevant(idx, idx.query(args.Context), -1, limit)
+	if len(matches) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No relevant thoughts found."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: t.formatRelevant(matches)}},
+		StructuredContent: map[string]any{"thoughts": matches},
+	}, nil
+}
+
+type ThinkAndRelateInput struct {
+	Thought  string   `json:"thought" jsonschema:"a thought to record"`
+	TTL      string   `json:"ttl,omitempty" jsonschema:"optional lifetime after which the thought expires, e.g. 30m; thoughts without a ttl never expire"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"optional tags to categorize the thought"`
+	Priority int      `json:"priority,omitempty" jsonschema:"optional priority, higher is more important"`
+	Category string   `json:"category,omitempty" jsonschema:"optional category of the thought, required if the server runs with -require-category"`
+	Kind     string   `json:"kind,omitempty" jsonschema:"optional kind of reasoning step: thought, action or observation"`
+
+	Confidence *float64 `json:"confidence,omitempty" jsonschema:"optional confidence in the thought from 0 (speculation) to 1 (certain)"`
+
+	References []string `json:"references,omitempty" jsonschema:"optional URLs, file paths or issue numbers such as #42 the thought refers to"`
+
+	ParentIndex int   `json:"parent_index,omitempty" jsonschema:"optional 1-based index of an earlier thought this one follows from; if the server runs with -auto-parent, defaults to the previous thought"`
+	DependsOn   []int `json:"depends_on,omitempty" jsonschema:"optional 1-based indices of earlier thoughts that must be resolved before this one"`
+
+	Limit int `json:"limit,omitempty" jsonschema:"maximum number of related thoughts, defaults to 3"`
+}
+
+// ThinkAndRelate is a tool that records a thought like Think and returns the
+// earlier thoughts most relevant to it, scored like get_relevant_thoughts.
+func (t *ThinkTool) ThinkAndRelate(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThinkAndRelateInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	if args.Limit < 0 {
+		return nil, toolError(CodeOutOfRange, "limit must not be negative")
+	}
+	limit := args.Limit
+	if limit == 0 {
+		limit = 3
+	}
+	res, err := t.record(sess, ThinkInput{
+		Thought:     args.Thought,
+		TTL:         args.TTL,
+		Tags:        args.Tags,
+		Priority:    args.Priority,
+		Category:    args.Category,
+		Kind:        args.Kind,
+		Confidence:  args.Confidence,
+		References:  args.References,
+		ParentIndex: args.ParentIndex,
+		DependsOn:   args.DependsOn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Recording may be paused.
+	if t.recordingPaused {
+		return res, nil
+	}
+
+	// Recording may have evicted thoughts, so the new one is looked up after.
+	n := len(t.thoughts) - 1
+	idx := t.relevanceIndex()
+	matches := t.relevant(idx, idx.vectors[n], n, limit)
+	text := "\nNo related earlier thoughts found."
+	if len(matches) > 0 {
+		text = "\nRelated earlier thoughts:\n\n" + t.formatRelevant(matches)
+	}
+	if c, ok := res.Content[0].(*mcp.TextContent); ok {
+		c.Text += text
+	}
+	res.StructuredContent = map[string]any{"index": n + 1, "related": matches}
+	return res, nil
+}
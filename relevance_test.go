@@ -0,0 +1,32 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestThinkAndRelateRecordsLikeThink(t *testing.T) {
+	tool := NewThinkTool(Config{RequireCategory: true}, nil)
+
+	_, err := tool.ThinkAndRelate(context.Background(), nil, &mcp.CallToolParamsFor[ThinkAndRelateInput]{Arguments: ThinkAndRelateInput{Thought: "the cache may be stale"}})
+	if err == nil {
+		t.Fatalf("think_and_relate without a category succeeded, want it to be rejected with -require-category")
+	}
+
+	confidence := 0.5
+	invoke(t, tool.ThinkAndRelate, ThinkAndRelateInput{Thought: "the cache may be stale", Category: "debugging"})
+	invoke(t, tool.ThinkAndRelate, ThinkAndRelateInput{Thought: "the stale cache causes the flake", Category: "debugging", Priority: 2, Confidence: &confidence, ParentIndex: 1, DependsOn: []int{1}})
+	if len(tool.thoughts) != 2 {
+		t.Fatalf("%d thoughts recorded, want 2", len(tool.thoughts))
+	}
+	got := tool.thoughts[1]
+	if got.Category != "debugging" || got.Priority != 2 || got.Confidence == nil || *got.Confidence != 0.5 || got.ParentIndex != 1 || len(got.DependsOn) != 1 {
+		t.Errorf("thought recorded by think_and_relate = %+v, want its category, priority, confidence, parent and dependencies kept", got)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"text/template"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// renderedThought is the data a -render-template is executed with.
+type renderedThought struct {
+	Index     int // 1-based
+	Timestamp string
+	Tags      []string
+	Text      string
+	Item      ThoughtItem
+}
+
+// parseRenderTemplate parses the template of -render-template, or returns
+// nil if it is empty.
+func parseRenderTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("thought").Parse(text)
+}
+
+// GetThoughtsTemplated is a tool that returns the thoughts recorded so far,
+// each rendered with the template of -render-template.
+func (t *ThinkTool) GetThoughtsTemplated(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.render == nil {
+		return nil, toolError(CodeNotFound, "no render template configured. Start the server with -render-template to use one.")
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		var b strings.Builder
+		err := t.render.Execute(&b, renderedThought{
+			Index:     i + 1,
+			Timestamp: thought.CreatedAt,
+			Tags:      thought.Tags,
+			Text:      thought.Thought,
+			Item:      thought.clone(),
+		})
+		if err != nil {
+			return nil, toolError(CodeInternal, "failed to render thought #%d: %v", i+1, err)
+		}
+		thoughts = append(thoughts, b.String())
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n")}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// loadReviewNotes reads the sidecar file of -review-file, a JSON object
+// mapping thought IDs to a reviewer note or a list of them. A missing file
+// means that there are no notes yet.
+func loadReviewNotes(path string) (map[string][]string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	notes := map[string][]string{}
+	for id, value := range raw {
+		var note string
+		if err := json.Unmarshal(value, &note); err == nil {
+			notes[id] = []string{note}
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(value, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: the notes of %q are neither a string nor a list of strings", path, id)
+		}
+		notes[id] = list
+	}
+	return notes, nil
+}
+
+// GetAnnotatedThoughts is a tool that returns the thoughts recorded so far
+// with the reviewer notes of the -review-file sidecar merged in. The sidecar
+// is read on every call, so reviewers can edit it while the server runs. If
+// it is malformed, the thoughts are returned without review notes along with
+// a warning.
+func (t *ThinkTool) GetAnnotatedThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	thoughts := []string{}
+	notes := map[string][]string{}
+	if t.cfg.ReviewFile == "" {
+		thoughts = append(thoughts, "Warning: no review notes, start the server with -review-file to load them.\n")
+	} else if loaded, err := loadReviewNotes(t.cfg.ReviewFile); err != nil {
+		slog.Warn("failed to load review notes", slog.String("path", t.cfg.ReviewFile), slog.Any("error", err))
+		thoughts = append(thoughts, fmt.Sprintf("Warning: review notes could not be loaded (%v).\n", err))
+	} else {
+		notes = loaded
+	}
+
+	for i, thought := range t.thoughts {
+		text := t.formatThought(i, thought)
+		for _, note := range notes[thought.ID] {
+			if note = strings.TrimSpace(note); note != "" {
+				text += fmt.Sprintf("  Review: %s\n", note)
+			}
+		}
+		thoughts = append(thoughts, text)
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n")}, nil
+}
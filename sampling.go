@@ -0,0 +1,119 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type SummarizeViaSamplingInput struct {
+	Prune     bool   `json:"prune,omitempty" jsonschema:"remove the summarized thoughts once the summary is recorded, except locked ones"`
+	MaxTokens int64  `json:"max_tokens,omitempty" jsonschema:"maximum length of the summary in tokens, defaults to 1024"`
+	Category  string `json:"category,omitempty" jsonschema:"optional category of the summary, required if the server runs with -require-category"`
+}
+
+// SummarizeViaSampling is a tool that asks the client's model to summarize
+// the log through MCP sampling and records the summary as a thought tagged
+// summary. The log is not locked while the client samples, so thoughts that
+// are recorded in the meantime are kept and not part of the summary. The
+// summary is recorded like any other thought, so it must pass the same
+// checks.
+func (t *ThinkTool) SummarizeViaSampling(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SummarizeViaSamplingInput]) (*mcp.CallToolResultFor[any], error) {
+	args := params.Arguments
+	maxTokens := args.MaxTokens
+	if maxTokens < 0 {
+		return nil, toolError(CodeOutOfRange, "max_tokens must not be negative")
+	}
+	if maxTokens == 0 {
+		maxTokens = 1024
+	}
+
+	t.mu.RLock()
+	if len(t.thoughts) == 0 {
+		t.mu.RUnlock()
+		return nil, t.errNoThoughts()
+	}
+	summarized := map[string]bool{}
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		summarized[thought.ID] = true
+		thoughts = append(thoughts, fmt.Sprintf("Thought #%d:\n%s\n", i+1, thought.Thought))
+	}
+	t.mu.RUnlock()
+
+	notSupported := "Sampling is not supported by the client, so the log could not be summarized. Summarize get_thoughts yourself and record the summary with think instead."
+	if sess == nil {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: notSupported}}}, nil
+	}
+	res, err := sess.CreateMessage(ctx, &mcp.CreateMessageParams{
+		MaxTokens:    maxTokens,
+		SystemPrompt: "You summarize the reasoning log of an agent. Keep the conclusions, open questions and decisions, and drop repetition.",
+		Messages: []*mcp.SamplingMessage{{
+			Role:    "user",
+			Content: &mcp.TextContent{Text: "Summarize the following thoughts:\n\n" + strings.Join(thoughts, "\n")},
+		}},
+	})
+	if err != nil {
+		// The SDK does not expose the client capabilities, so a failed
+		// request is the only way to tell that sampling is unsupported. The
+		// error is passed on because the request may also fail for other
+		// reasons.
+		slog.Info("sampling request failed", slog.Any("err", err))
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("%s (%v)", notSupported, err)}}}, nil
+	}
+	content, ok := res.Content.(*mcp.TextContent)
+	if !ok || strings.TrimSpace(content.Text) == "" {
+		return nil, toolError(CodeInternal, "the client returned no text summary")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.recordSummary(sess, content.Text, summarized, args)
+}
+
+// recordSummary records summary as a thought tagged summary that summarizes
+// the thoughts with the given IDs, and removes them if args.Prune is set.
+// It must be called with t.mu held.
+func (t *ThinkTool) recordSummary(sess *mcp.ServerSession, summary string, summarized map[string]bool, args SummarizeViaSamplingInput) (*mcp.CallToolResultFor[any], error) {
+	// The summary is recorded before pruning, so that nothing is removed if
+	// it is rejected.
+	out, err := t.record(sess, ThinkInput{Thought: strings.TrimSpace(summary), Tags: []string{"summary"}, Category: args.Category})
+	if err != nil {
+		return nil, err
+	}
+	// Recording may be paused.
+	if t.recordingPaused {
+		return out, nil
+	}
+	recorded := out.Content[0].(*mcp.TextContent).Text
+
+	pruned, warning := 0, ""
+	if args.Prune {
+		kept := []int{}
+		for i, thought := range t.thoughts {
+			if !summarized[thought.ID] || thought.Locked {
+				kept = append(kept, i)
+			}
+		}
+		if pruned = len(t.thoughts) - len(kept); pruned > 0 {
+			t.rearrange(kept)
+			warning = t.persist(sess)
+		}
+	}
+
+	// The summary is the last thought, pruning keeps the order.
+	text := fmt.Sprintf("Recorded a summary of %d thoughts as thought #%d.", len(summarized), len(t.thoughts))
+	if pruned > 0 {
+		text += fmt.Sprintf(" Removed %d summarized thoughts.", pruned)
+	}
+	text += "\n" + recorded
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text + warning}}}, nil
+}
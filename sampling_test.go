@@ -0,0 +1,30 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestSummaryIsRecordedLikeThink(t *testing.T) {
+	tool := NewThinkTool(Config{RequireCategory: true}, realClock{})
+	invoke(t, tool.Think, ThinkInput{Thought: "the cache may be stale", Category: "debugging"})
+	summarized := map[string]bool{tool.thoughts[0].ID: true}
+
+	if _, err := tool.recordSummary(nil, "the cache is the culprit", summarized, SummarizeViaSamplingInput{Prune: true}); err == nil {
+		t.Fatalf("recording a summary without a category succeeded, want it to be rejected with -require-category")
+	}
+	if len(tool.thoughts) != 1 || tool.thoughts[0].Thought != "the cache may be stale" {
+		t.Fatalf("thoughts after the rejected summary = %+v, want the log to be unchanged", tool.thoughts)
+	}
+
+	if _, err := tool.recordSummary(nil, "the cache is the culprit", summarized, SummarizeViaSamplingInput{Prune: true, Category: "debugging"}); err != nil {
+		t.Fatalf("failed to record the summary: %v", err)
+	}
+	if len(tool.thoughts) != 1 {
+		t.Fatalf("%d thoughts after the summary, want only the summary", len(tool.thoughts))
+	}
+	if got := tool.thoughts[0]; got.Thought != "the cache is the culprit" || got.Category != "debugging" || got.Tags[0] != "summary" {
+		t.Errorf("summary = %+v, want it tagged summary with category debugging", got)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+)
+
+// schemaDraft is the only JSON Schema version thoughts can be validated
+// against.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// loadThoughtSchema reads the JSON schema of -thought-schema that recorded
+// thoughts must conform to.
+func (t *ThinkTool) loadThoughtSchema() error {
+	if t.cfg.ThoughtSchema == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(t.cfg.ThoughtSchema)
+	if err != nil {
+		return fmt.Errorf("failed to read thought schema: %w", err)
+	}
+	var s jsonschema.Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("failed to parse thought schema %s: %w", t.cfg.ThoughtSchema, err)
+	}
+	if s.Schema != "" && s.Schema != schemaDraft {
+		return fmt.Errorf("thought schema %s uses %s, only %s is supported", t.cfg.ThoughtSchema, s.Schema, schemaDraft)
+	}
+	resolved, err := s.Resolve(nil)
+	if err != nil {
+		return fmt.Errorf("invalid thought schema %s: %w", t.cfg.ThoughtSchema, err)
+	}
+	t.schema = resolved
+	return nil
+}
+
+// checkSchema reports why thought does not conform to the thought schema, or
+// nil if it does or there is no schema.
+func (t *ThinkTool) checkSchema(thought string) error {
+	if t.schema == nil {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(thought), &v); err != nil {
+		return toolError(CodeInvalidInput, "the thought must be a JSON document conforming to the thought schema, but it is not valid JSON: %v", err)
+	}
+	if err := t.schema.Validate(v); err != nil {
+		return toolError(CodeInvalidInput, "the thought does not conform to the thought schema: %v", err)
+	}
+	return nil
+}
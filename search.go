@@ -0,0 +1,453 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// normalize folds case and collapses runs of whitespace so that thoughts that
+// only differ in formatting compare equal.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+type HasThoughtInput struct {
+	Text string `json:"text" jsonschema:"the thought text to look for"`
+	Mode string `json:"mode,omitempty" jsonschema:"exact (default) compares the text as is, normalized ignores case and whitespace differences"`
+}
+
+// HasThoughtResult is the structured result of the has_thought tool.
+type HasThoughtResult struct {
+	Exists bool `json:"exists"`
+	Index  int  `json:"index,omitempty"` // 1-based index of the first match
+}
+
+// HasThought is a tool that reports whether a thought with the given text has
+// already been recorded.
+func (t *ThinkTool) HasThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[HasThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	if len(args.Text) == 0 {
+		return nil, toolError(CodeEmptyInput, "no text provided")
+	}
+
+	var equal func(a, b string) bool
+	switch args.Mode {
+	case "", "exact":
+		equal = func(a, b string) bool { return a == b }
+	case "normalized":
+		equal = func(a, b string) bool { return normalize(a) == normalize(b) }
+	default:
+		return nil, toolError(CodeInvalidInput, "unknown mode %q, expected exact or normalized", args.Mode)
+	}
+
+	for i, thought := range t.thoughts {
+		if equal(thought.Thought, args.Text) {
+			return &mcp.CallToolResultFor[any]{
+				Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Yes, the thought already exists as thought #%d.", i+1)}},
+				StructuredContent: HasThoughtResult{Exists: true, Index: i + 1},
+			}, nil
+		}
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: "No, the thought has not been recorded yet."}},
+		StructuredContent: HasThoughtResult{Exists: false},
+	}, nil
+}
+
+// fuzzyMinSimilarity is the minimum similarity for two words that are not
+// equal to still count as a (partial) match, e.g. for typos.
+const fuzzyMinSimilarity = 0.75
+
+// fuzzyScore scores how relevant text is to the query tokens, from 0 to 1.
+// Each query token contributes 1 if it occurs in the text, or its similarity
+// to the closest word of the text if that is at least fuzzyMinSimilarity.
+func fuzzyScore(query []string, text string) float64 {
+	words := tokenize(text)
+	if len(query) == 0 || len(words) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, q := range query {
+		best := 0.0
+		for _, w := range words {
+			best = max(best, similarity(q, w))
+			if best == 1 {
+				break
+			}
+		}
+		if best >= fuzzyMinSimilarity {
+			total += best
+		}
+	}
+	return total / float64(len(query))
+}
+
+type FuzzySearchThoughtsInput struct {
+	Query     string  `json:"query" jsonschema:"the text to search for; typos and partial matches are tolerated"`
+	Limit     int     `json:"limit,omitempty" jsonschema:"maximum number of results, defaults to 5"`
+	Threshold float64 `json:"threshold,omitempty" jsonschema:"minimum relevance score between 0 and 1, defaults to 0.3"`
+}
+
+// FuzzySearchThoughts is a tool that returns the thoughts most relevant to a
+// query, ranked by a fuzzy relevance score.
+func (t *ThinkTool) FuzzySearchThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[FuzzySearchThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	query := tokenize(args.Query)
+	if len(query) == 0 {
+		return nil, toolError(CodeEmptyInput, "no query provided")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	threshold := args.Threshold
+	if threshold <= 0 {
+		threshold = 0.3
+	}
+	if threshold > 1 {
+		return nil, toolError(CodeOutOfRange, "invalid threshold %v, expected a value between 0 and 1", threshold)
+	}
+
+	type match struct {
+		index int
+		score float64
+	}
+	matches := []match{}
+	for i, thought := range t.thoughts {
+		if score := fuzzyScore(query, thought.Thought); score >= threshold {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+	if len(matches) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}}, nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := []string{}
+	for _, m := range matches {
+		thought := t.thoughts[m.index]
+		results = append(results, fmt.Sprintf("Thought #%d (score %.2f) at %s:\n%s\n", m.index+1, m.score, thought.CreatedAt, thought.Thought))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(results, "\n")}}}, nil
+}
+
+// globRegexp translates a glob pattern into an anchored regular expression.
+// It follows path.Match, adapted for arbitrary text: '*' matches any sequence
+// of characters including '/' and newlines, '?' matches any single character,
+// '[...]' matches a character class ('[!...]' or '[^...]' negates it) and '\'
+// escapes the next character.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	r := []rune(pattern)
+	for i := 0; i < len(r); i++ {
+		switch c := r[i]; c {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i++; i == len(r) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			b.WriteString(regexp.QuoteMeta(string(r[i])))
+		case '[':
+			end := i + 1
+			if end < len(r) && (r[end] == '!' || r[end] == '^') {
+				end++
+			}
+			if end < len(r) && r[end] == ']' {
+				end++
+			}
+			for end < len(r) && r[end] != ']' {
+				end++
+			}
+			if end == len(r) {
+				return nil, fmt.Errorf("unterminated character class at offset %d", i)
+			}
+			class := r[i+1 : end]
+			b.WriteByte('[')
+			if len(class) > 0 && (class[0] == '!' || class[0] == '^') {
+				b.WriteByte('^')
+				class = class[1:]
+			}
+			for _, c := range class {
+				if c == '-' {
+					b.WriteRune(c)
+					continue
+				}
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+			b.WriteByte(']')
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
+
+type GlobSearchThoughtsInput struct {
+	Pattern string `json:"pattern" jsonschema:"glob pattern matched against the whole thought: * matches any text, ? any single character, [abc] or [a-z] a character class, e.g. *cache*"`
+}
+
+// GlobSearchThoughts is a tool that returns the thoughts whose text matches a
+// glob pattern.
+func (t *ThinkTool) GlobSearchThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GlobSearchThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	pattern := params.Arguments.Pattern
+	if len(pattern) == 0 {
+		return nil, toolError(CodeEmptyInput, "no pattern provided")
+	}
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return nil, toolError(CodeInvalidInput, "invalid glob pattern %q: %v", pattern, err)
+	}
+
+	results := []string{}
+	for i, thought := range t.thoughts {
+		if re.MatchString(thought.Thought) {
+			results = append(results, t.formatThought(i, thought))
+		}
+	}
+	if len(results) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(results, "\n")}}}, nil
+}
+
+type GetThoughtsByTagPatternInput struct {
+	Pattern string `json:"pattern" jsonschema:"regular expression matched against each tag, unanchored unless it uses ^ and $, e.g. ^area/"`
+}
+
+// GetThoughtsByTagPattern is a tool that returns the thoughts with at least one
+// tag matching a regular expression.
+func (t *ThinkTool) GetThoughtsByTagPattern(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsByTagPatternInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	pattern := params.Arguments.Pattern
+	if len(pattern) == 0 {
+		return nil, toolError(CodeEmptyInput, "no pattern provided")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, toolError(CodeInvalidInput, "invalid tag pattern %q: %v", pattern, err)
+	}
+
+	results := []string{}
+	for i, thought := range t.thoughts {
+		if slices.ContainsFunc(thought.Tags, re.MatchString) {
+			results = append(results, t.formatThought(i, thought))
+		}
+	}
+	if len(results) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(results, "\n")}}}, nil
+}
+
+type RandomThoughtInput struct {
+	Seed int64 `json:"seed,omitempty" jsonschema:"seed of the random choice, the same seed picks the same thought of the same log; 0 picks a different thought on every call"`
+}
+
+// RandomThoughtResult is the structured result of the random_thought tool.
+type RandomThoughtResult struct {
+	Index   int         `json:"index"` // 1-based index of the chosen thought
+	Thought ThoughtItem `json:"thought"`
+}
+
+// RandomThought is a tool that returns a thought chosen uniformly at random,
+// e.g. to resurface an old idea.
+func (t *ThinkTool) RandomThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[RandomThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := t.clock.Now()
+	live := []int{}
+	for i, thought := range t.thoughts {
+		if !thought.expired(now) {
+			live = append(live, i)
+		}
+	}
+	if len(live) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	var i int
+	if seed := uint64(params.Arguments.Seed); seed != 0 {
+		i = live[rand.New(rand.NewPCG(seed, seed)).IntN(len(live))]
+	} else {
+		i = live[rand.IntN(len(live))]
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: t.formatThought(i, t.thoughts[i])}},
+		StructuredContent: RandomThoughtResult{Index: i + 1, Thought: t.thoughts[i].clone()},
+	}, nil
+}
+
+type GetThoughtsReferencingInput struct {
+	Reference string `json:"reference" jsonschema:"the reference to look for, matched as a substring of the references of each thought, e.g. auth.go or #42"`
+}
+
+// GetThoughtsReferencing is a tool that returns the thoughts citing a
+// reference.
+func (t *ThinkTool) GetThoughtsReferencing(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsReferencingInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	ref := strings.TrimSpace(params.Arguments.Reference)
+	if ref == "" {
+		return nil, toolError(CodeEmptyInput, "no reference provided")
+	}
+
+	results := []string{}
+	for i, thought := range t.thoughts {
+		if slices.ContainsFunc(thought.References, func(r string) bool { return strings.Contains(r, ref) }) {
+			results = append(results, t.formatThought(i, thought))
+		}
+	}
+	if len(results) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No thoughts reference %q.", ref)}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(results, "\n")}}}, nil
+}
+
+// querySortKeys are the sort keys of query_thoughts.
+var querySortKeys = []string{"created", "updated", "priority", "length"}
+
+type QueryThoughtsInput struct {
+	Tags   []string `json:"tags,omitempty" jsonschema:"only thoughts with all of these tags"`
+	Query  string   `json:"query,omitempty" jsonschema:"only thoughts containing this text, matched case-insensitively as a substring"`
+	Since  string   `json:"since,omitempty" jsonschema:"only thoughts recorded at or after this RFC 3339 time, e.g. 2025-06-01T00:00:00Z"`
+	Until  string   `json:"until,omitempty" jsonschema:"only thoughts recorded before this RFC 3339 time"`
+	SortBy string   `json:"sort_by,omitempty" jsonschema:"created (default), updated, priority or length"`
+	Desc   bool     `json:"desc,omitempty" jsonschema:"sort in descending instead of ascending order"`
+	Limit  int      `json:"limit,omitempty" jsonschema:"maximum number of thoughts to return, defaults to 20"`
+	Offset int      `json:"offset,omitempty" jsonschema:"number of matching thoughts to skip, for paging"`
+}
+
+// QueryThoughtsResult is the structured result of the query_thoughts tool.
+type QueryThoughtsResult struct {
+	Total    int           `json:"total"`   // Number of matching thoughts before paging
+	Indices  []int         `json:"indices"` // 1-based indices of the returned thoughts
+	Thoughts []ThoughtItem `json:"thoughts"`
+}
+
+// QueryThoughts is a tool that filters, sorts and pages the thoughts in one
+// call. A thought matches if it passes all given filters: it has all tags,
+// contains the query and was recorded within [since, until). The matches are
+// sorted by the sort key, ties in log order, and the page of limit matches
+// after offset is returned along with the total number of matches.
+func (t *ThinkTool) QueryThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[QueryThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	if args.Limit < 0 || args.Offset < 0 {
+		return nil, toolError(CodeOutOfRange, "limit and offset must not be negative")
+	}
+	limit := args.Limit
+	if limit == 0 {
+		limit = 20
+	}
+	sortBy := args.SortBy
+	if sortBy == "" {
+		sortBy = "created"
+	}
+	if !slices.Contains(querySortKeys, sortBy) {
+		return nil, toolError(CodeInvalidInput, "invalid sort_by %q, expected one of %s", args.SortBy, strings.Join(querySortKeys, ", "))
+	}
+	var since, until time.Time
+	for _, bound := range []struct {
+		name, value string
+		t           *time.Time
+	}{{"since", args.Since, &since}, {"until", args.Until, &until}} {
+		if bound.value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return nil, toolError(CodeInvalidInput, "invalid %s %q, expected an RFC 3339 time such as 2025-06-01T00:00:00Z", bound.name, bound.value)
+		}
+		*bound.t = at
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	tags := cleanTags(args.Tags)
+	query := normalize(args.Query)
+	matches := []int{}
+	created := make([]time.Time, len(t.thoughts))
+	for i, thought := range t.thoughts {
+		at, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		created[i] = at
+		switch {
+		case slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(thought.Tags, tag) }),
+			query != "" && !strings.Contains(normalize(thought.Thought), query),
+			!since.IsZero() && at.Before(since),
+			!until.IsZero() && !at.Before(until):
+			continue
+		}
+		matches = append(matches, i)
+	}
+
+	slices.SortStableFunc(matches, func(a, b int) int {
+		var c int
+		switch sortBy {
+		case "created":
+			c = created[a].Compare(created[b])
+		case "updated":
+			c = t.thoughts[a].touchedAt().Compare(t.thoughts[b].touchedAt())
+		case "priority":
+			c = cmp.Compare(t.thoughts[a].Priority, t.thoughts[b].Priority)
+		case "length":
+			c = cmp.Compare(utf8.RuneCountInString(t.thoughts[a].Thought), utf8.RuneCountInString(t.thoughts[b].Thought))
+		}
+		if args.Desc {
+			c = -c
+		}
+		return c
+	})
+
+	result := QueryThoughtsResult{Total: len(matches), Indices: []int{}, Thoughts: []ThoughtItem{}}
+	page := matches[min(args.Offset, len(matches)):min(args.Offset+limit, len(matches))]
+	parts := []string{fmt.Sprintf("%d matching thought(s), showing %d from offset %d.\n", len(matches), len(page), args.Offset)}
+	for _, i := range page {
+		result.Indices = append(result.Indices, i+1)
+		result.Thoughts = append(result.Thoughts, t.thoughts[i].clone())
+		parts = append(parts, t.formatThought(i, t.thoughts[i]))
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(parts, "\n"), StructuredContent: result}, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sentimentLexicon maps the English words that sentiment recognizes to their
+// polarity. Positive words express confidence or progress, negative words
+// being stuck, confused or frustrated. The list is deliberately small and
+// tuned to reasoning logs rather than general text.
+var sentimentLexicon = map[string]int{
+	"clear": 1, "confident": 1, "confirmed": 1, "correct": 1, "done": 1,
+	"easy": 1, "fixed": 1, "good": 1, "great": 1, "progress": 1,
+	"resolved": 1, "solved": 1, "sure": 1, "works": 1, "working": 1,
+
+	"blocked": -1, "broken": -1, "bug": -1, "confused": -1, "confusing": -1,
+	"error": -1, "fail": -1, "failed": -1, "fails": -1, "frustrating": -1,
+	"hard": -1, "stuck": -1, "unclear": -1, "unsure": -1, "wrong": -1,
+}
+
+// sentimentNegations flip the polarity of the word following them. The "t"
+// is what tokenize leaves of the n't of "doesn't".
+var sentimentNegations = map[string]bool{"no": true, "not": true, "never": true, "cannot": true, "t": true}
+
+// sentiment returns a heuristic sentiment score of text from -1 (all
+// recognized words are negative) to 1 (all are positive): the number of
+// positive minus the number of negative words of sentimentLexicon, divided by
+// the number of recognized words. A word directly preceded by a word of
+// sentimentNegations counts with the opposite polarity. Text without any
+// recognized word scores 0.
+func sentiment(text string) float64 {
+	sum, hits := 0, 0
+	negated := false
+	for _, w := range tokenize(text) {
+		if polarity, ok := sentimentLexicon[w]; ok {
+			if negated {
+				polarity = -polarity
+			}
+			sum += polarity
+			hits++
+		}
+		negated = sentimentNegations[w]
+	}
+	if hits == 0 {
+		return 0
+	}
+	return float64(sum) / float64(hits)
+}
+
+type GetThoughtsBySentimentInput struct {
+	Threshold float64 `json:"threshold,omitempty" jsonschema:"the score between -1 and 1 to compare against, defaults to 0"`
+	Below     bool    `json:"below,omitempty" jsonschema:"return the thoughts scoring below the threshold, e.g. where the reasoning got stuck, instead of above it"`
+}
+
+// SentimentThought is a thought with its sentiment score.
+type SentimentThought struct {
+	Index     int     `json:"index"`
+	Sentiment float64 `json:"sentiment"`
+	Thought   string  `json:"thought"`
+}
+
+// GetThoughtsBySentiment is a tool that returns the thoughts whose heuristic
+// sentiment score (see sentiment) is above, or with below set, below a
+// threshold. The score is a rough experimental signal of the tone of the
+// reasoning, not an analysis of its content.
+func (t *ThinkTool) GetThoughtsBySentiment(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsBySentimentInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	if args.Threshold < -1 || args.Threshold > 1 {
+		return nil, toolError(CodeOutOfRange, "invalid threshold %v, expected a value between -1 and 1", args.Threshold)
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	matches := []SentimentThought{}
+	results := []string{"Sentiment scores are a heuristic based on a small word list, treat them as a rough signal.\n"}
+	for i, thought := range t.thoughts {
+		score := sentiment(thought.Thought)
+		if args.Below && score >= args.Threshold || !args.Below && score <= args.Threshold {
+			continue
+		}
+		matches = append(matches, SentimentThought{Index: i + 1, Sentiment: score, Thought: thought.Thought})
+		results = append(results, fmt.Sprintf("Thought #%d (sentiment %+.2f) at %s:\n%s\n", i+1, score, thought.CreatedAt, thought.Thought))
+	}
+	if len(matches) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}, StructuredContent: map[string]any{"thoughts": matches}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(results, "\n")}},
+		StructuredContent: map[string]any{"thoughts": matches},
+	}, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionState is the state the ThinkTool keeps per session.
+type sessionState struct {
+	tokens    int  // Estimated tokens recorded since the last clear
+	ephemeral bool // Whether set_persistence is off, so that thoughts are recorded as scratch
+}
+
+// sessionKey returns the key of the state of sess: its ID, or its address for
+// sessions without an ID such as those of stdio, and "" for a nil sess. The
+// address cannot be reused while the state exists, since the state is dropped
+// only once the session ended.
+func sessionKey(sess *mcp.ServerSession) string {
+	if sess == nil {
+		return ""
+	}
+	if id := sess.ID(); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%p", sess)
+}
+
+// session returns the state of sess, creating it on first use. The state is
+// dropped once the session ends, so that a server serving many HTTP sessions
+// does not accumulate the state of closed ones. It must be called with t.mu
+// held for writing.
+func (t *ThinkTool) session(sess *mcp.ServerSession) *sessionState {
+	id := sessionKey(sess)
+	if s, ok := t.sessions[id]; ok {
+		return s
+	}
+	if t.sessions == nil {
+		t.sessions = map[string]*sessionState{}
+	}
+	s := &sessionState{}
+	t.sessions[id] = s
+	if sess != nil {
+		go func() {
+			sess.Wait()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(t.sessions, id)
+		}()
+	}
+	return s
+}
+
+// resetSessionTokens restarts the token count of every session, e.g. after
+// the log was cleared. It must be called with t.mu held for writing.
+func (t *ThinkTool) resetSessionTokens() {
+	for _, s := range t.sessions {
+		s.tokens = 0
+	}
+}
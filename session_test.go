@@ -0,0 +1,40 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSessionStateIsDroppedOnClose(t *testing.T) {
+	tool := NewThinkTool(Config{SessionTokenBudget: 1000}, nil)
+	server := mcp.NewServer(&mcp.Implementation{Name: serverName}, nil)
+	addTool(server, &mcp.Tool{Name: "think"}, tool.Think)
+	cs := connect(t, server)
+
+	callText(t, cs, "think", map[string]any{"thought": "counted against the budget"})
+	tool.mu.RLock()
+	n := len(tool.sessions)
+	tool.mu.RUnlock()
+	if n != 1 {
+		t.Fatalf("tracked sessions after a thought = %d, want 1", n)
+	}
+
+	cs.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		tool.mu.RLock()
+		n = len(tool.sessions)
+		tool.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tracked sessions after the session closed = %d, want 0", n)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Share is a read-only copy of the thought log stored under a short ID.
+type Share struct {
+	CreatedAt string        `json:"created_at"`
+	ExpiresAt string        `json:"expires_at,omitempty"`
+	Thoughts  []ThoughtItem `json:"thoughts"`
+}
+
+// expired reports whether the share has expired at now.
+func (s Share) expired(now time.Time) bool {
+	expiresAt, err := time.Parse(time.RFC3339, s.ExpiresAt)
+	return err == nil && !now.Before(expiresAt)
+}
+
+// pruneShares drops the expired shares.
+func (t *ThinkTool) pruneShares() {
+	now := t.clock.Now()
+	for id, share := range t.shares {
+		if share.expired(now) {
+			delete(t.shares, id)
+		}
+	}
+}
+
+// CreateShareLink is a tool that stores a copy of the current thoughts under a
+// newly generated short ID. Later changes to the log do not affect the share.
+// With -share-ttl, the share expires after that period.
+func (t *ThinkTool) CreateShareLink(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	t.pruneShares()
+	if t.shares == nil {
+		t.shares = map[string]Share{}
+	}
+	id := newID()[:8]
+	for _, taken := t.shares[id]; taken; _, taken = t.shares[id] {
+		id = newID()[:8]
+	}
+	now := t.clock.Now()
+	share := Share{
+		CreatedAt: now.Format(time.RFC3339),
+		Thoughts:  cloneThoughts(t.thoughts),
+	}
+	expiry := ""
+	if ttl := t.cfg.ShareTTL; ttl > 0 {
+		share.ExpiresAt = now.Add(ttl).Format(time.RFC3339)
+		expiry = fmt.Sprintf(" It expires at %s.", share.ExpiresAt)
+	}
+	t.shares[id] = share
+	warning := t.persist(sess)
+
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Shared %d thought(s) as %s.%s%s", len(share.Thoughts), id, expiry, warning)}},
+		StructuredContent: map[string]string{"id": id, "expires_at": share.ExpiresAt},
+	}, nil
+}
+
+type GetSharedInput struct {
+	ID string `json:"id" jsonschema:"the ID returned by create_share_link"`
+}
+
+// GetShared is a tool that returns the thoughts of a share.
+func (t *ThinkTool) GetShared(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetSharedInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	id := params.Arguments.ID
+	if len(id) == 0 {
+		return nil, toolError(CodeEmptyInput, "no share ID provided")
+	}
+	share, ok := t.shares[id]
+	if !ok || share.expired(t.clock.Now()) {
+		return nil, toolError(CodeNotFound, "no share with ID %q, it may have expired", id)
+	}
+
+	thoughts := []string{fmt.Sprintf("Shared at %s:", share.CreatedAt)}
+	for i, thought := range share.Thoughts {
+		thoughts = append(thoughts, t.formatThought(i, thought))
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n"), StructuredContent: share}, nil
+}
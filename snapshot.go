@@ -0,0 +1,283 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Snapshot is a named copy of the thought log.
+type Snapshot struct {
+	CreatedAt string        `json:"created_at"`
+	Thoughts  []ThoughtItem `json:"thoughts"`
+}
+
+type SnapshotInput struct {
+	Name string `json:"name" jsonschema:"the name of the snapshot"`
+}
+
+// CreateSnapshot is a tool that saves a copy of the current thoughts under a
+// name, replacing an existing snapshot with the same name.
+func (t *ThinkTool) CreateSnapshot(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SnapshotInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := strings.TrimSpace(params.Arguments.Name)
+	if name == "" {
+		return nil, toolError(CodeEmptyInput, "no snapshot name provided")
+	}
+
+	if t.snapshots == nil {
+		t.snapshots = map[string]Snapshot{}
+	}
+	_, replaced := t.snapshots[name]
+	t.snapshots[name] = Snapshot{
+		CreatedAt: t.clock.Now().Format(time.RFC3339),
+		Thoughts:  cloneThoughts(t.thoughts),
+	}
+	warning := t.persist(sess)
+
+	verb := "Created"
+	if replaced {
+		verb = "Replaced"
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("%s snapshot %q with %d thought(s).%s", verb, name, len(t.thoughts), warning)}}}, nil
+}
+
+type RestoreSnapshotInput struct {
+	Name    string `json:"name" jsonschema:"the name of the snapshot"`
+	Confirm bool   `json:"confirm,omitempty" jsonschema:"confirm replacing the log even though it contains locked thoughts"`
+}
+
+// RestoreSnapshot is a tool that replaces the current thoughts with the
+// contents of a named snapshot. Like ClearThoughts, it refuses to drop locked
+// thoughts unless confirmed.
+func (t *ThinkTool) RestoreSnapshot(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[RestoreSnapshotInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := strings.TrimSpace(params.Arguments.Name)
+	snapshot, ok := t.snapshots[name]
+	if !ok {
+		return nil, toolError(CodeNotFound, "no snapshot named %q. Use list_snapshots to see the available snapshots.", name)
+	}
+	if err := t.checkClear(params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+
+	t.thoughts = cloneThoughts(snapshot.Thoughts)
+	t.lastCleared = nil
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Restored snapshot %q with %d thought(s).%s", name, len(t.thoughts), warning)}}}, nil
+}
+
+// ListSnapshots is a tool that lists the available snapshots.
+func (t *ThinkTool) ListSnapshots(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.snapshots) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No snapshots created yet."}}}, nil
+	}
+
+	names := []string{}
+	for name := range t.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		snapshot := t.snapshots[name]
+		lines = append(lines, fmt.Sprintf("- %s: %d thought(s), created at %s", name, len(snapshot.Thoughts), snapshot.CreatedAt))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}}}, nil
+}
+
+type DiffSnapshotsInput struct {
+	From string `json:"from" jsonschema:"the name of the older snapshot"`
+	To   string `json:"to" jsonschema:"the name of the newer snapshot"`
+}
+
+// SnapshotDiff is the structured result of the diff_snapshots tool. It lists
+// thought IDs.
+type SnapshotDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// DiffSnapshots is a tool that reports the thoughts added, removed and
+// modified between two snapshots. Thoughts are matched by ID, so a thought
+// that only moved to another position is not reported.
+func (t *ThinkTool) DiffSnapshots(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[DiffSnapshotsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	names := []string{strings.TrimSpace(params.Arguments.From), strings.TrimSpace(params.Arguments.To)}
+	snapshots := make([]Snapshot, 2)
+	for i, name := range names {
+		snapshot, ok := t.snapshots[name]
+		if !ok {
+			return nil, toolError(CodeNotFound, "no snapshot named %q. Use list_snapshots to see the available snapshots.", name)
+		}
+		snapshots[i] = snapshot
+	}
+
+	older := map[string]ThoughtItem{}
+	for _, thought := range snapshots[0].Thoughts {
+		older[thought.ID] = thought
+	}
+	newer := map[string]bool{}
+	diff := SnapshotDiff{Added: []string{}, Removed: []string{}, Modified: []string{}}
+	lines := []string{}
+	for i, thought := range snapshots[1].Thoughts {
+		newer[thought.ID] = true
+		old, ok := older[thought.ID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, thought.ID)
+			lines = append(lines, fmt.Sprintf("+ %s (#%d in %s): %s", thought.ID, i+1, names[1], truncate(thought.Thought, 80)))
+		case !reflect.DeepEqual(old, thought):
+			diff.Modified = append(diff.Modified, thought.ID)
+			lines = append(lines, fmt.Sprintf("~ %s (#%d in %s): %s", thought.ID, i+1, names[1], truncate(thought.Thought, 80)))
+		}
+	}
+	for i, thought := range snapshots[0].Thoughts {
+		if !newer[thought.ID] {
+			diff.Removed = append(diff.Removed, thought.ID)
+			lines = append(lines, fmt.Sprintf("- %s (#%d in %s): %s", thought.ID, i+1, names[0], truncate(thought.Thought, 80)))
+		}
+	}
+
+	summary := fmt.Sprintf("From %q to %q: %d added, %d removed, %d modified.", names[0], names[1], len(diff.Added), len(diff.Removed), len(diff.Modified))
+	if len(lines) > 0 {
+		summary += "\n\n" + strings.Join(lines, "\n")
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: summary}},
+		StructuredContent: diff,
+	}, nil
+}
+
+type CompareToBaselineInput struct {
+	Baseline string `json:"baseline" jsonschema:"a JSON document as produced by export_json, or a JSON array of thoughts"`
+}
+
+// BaselineChange pairs a thought with the baseline thought it replaced.
+type BaselineChange struct {
+	Index         int `json:"index"`          // 1-based index in the current log
+	BaselineIndex int `json:"baseline_index"` // 1-based index in the baseline
+}
+
+// BaselineDiff is the structured result of the compare_to_baseline tool.
+type BaselineDiff struct {
+	Unchanged int              `json:"unchanged"`
+	New       []int            `json:"new"`     // 1-based indices in the current log
+	Missing   []int            `json:"missing"` // 1-based indices in the baseline
+	Changed   []BaselineChange `json:"changed"`
+}
+
+// baselineExamples is the number of examples compare_to_baseline shows per
+// kind of difference.
+const baselineExamples = 5
+
+// CompareToBaseline is a tool that compares the current log to a baseline
+// export, e.g. of an earlier run of the same task. Thoughts are matched by
+// their normalized text regardless of position; a thought whose text differs
+// from the baseline thought with the same ID counts as changed, the other
+// unmatched thoughts as new or missing.
+func (t *ThinkTool) CompareToBaseline(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[CompareToBaselineInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	data := strings.TrimSpace(params.Arguments.Baseline)
+	if data == "" {
+		return nil, toolError(CodeEmptyInput, "no baseline provided")
+	}
+	var baseline []ThoughtItem
+	if strings.HasPrefix(data, "[") {
+		if err := json.Unmarshal([]byte(data), &baseline); err != nil {
+			return nil, toolError(CodeInvalidInput, "invalid baseline: %v", err)
+		}
+	} else {
+		var doc exportDoc
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, toolError(CodeInvalidInput, "invalid baseline: %v", err)
+		}
+		baseline = doc.Thoughts
+	}
+
+	unmatched := map[string][]int{} // Normalized text -> positions in baseline
+	for i, thought := range baseline {
+		key := normalize(thought.Thought)
+		unmatched[key] = append(unmatched[key], i)
+	}
+	diff := BaselineDiff{New: []int{}, Missing: []int{}, Changed: []BaselineChange{}}
+	matched := make([]bool, len(baseline))
+	rest := []int{}
+	for i, thought := range t.thoughts {
+		key := normalize(thought.Thought)
+		if positions := unmatched[key]; len(positions) > 0 {
+			matched[positions[0]] = true
+			unmatched[key] = positions[1:]
+			diff.Unchanged++
+			continue
+		}
+		rest = append(rest, i)
+	}
+	byID := map[string]int{}
+	for i, thought := range baseline {
+		if !matched[i] && thought.ID != "" {
+			byID[thought.ID] = i
+		}
+	}
+	for _, i := range rest {
+		if j, ok := byID[t.thoughts[i].ID]; ok && !matched[j] {
+			matched[j] = true
+			diff.Changed = append(diff.Changed, BaselineChange{Index: i + 1, BaselineIndex: j + 1})
+			continue
+		}
+		diff.New = append(diff.New, i+1)
+	}
+	for i := range baseline {
+		if !matched[i] {
+			diff.Missing = append(diff.Missing, i+1)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%d unchanged, %d new, %d missing, %d changed.", diff.Unchanged, len(diff.New), len(diff.Missing), len(diff.Changed))}
+	for n, i := range diff.New[:min(len(diff.New), baselineExamples)] {
+		if n == 0 {
+			lines = append(lines, "", "New:")
+		}
+		lines = append(lines, fmt.Sprintf("+ #%d: %s", i, truncate(t.thoughts[i-1].Thought, 80)))
+	}
+	for n, i := range diff.Missing[:min(len(diff.Missing), baselineExamples)] {
+		if n == 0 {
+			lines = append(lines, "", "Missing:")
+		}
+		lines = append(lines, fmt.Sprintf("- baseline #%d: %s", i, truncate(baseline[i-1].Thought, 80)))
+	}
+	for n, c := range diff.Changed[:min(len(diff.Changed), baselineExamples)] {
+		if n == 0 {
+			lines = append(lines, "", "Changed:")
+		}
+		lines = append(lines, fmt.Sprintf("~ #%d (baseline #%d): %s", c.Index, c.BaselineIndex, truncate(t.thoughts[c.Index-1].Thought, 80)),
+			fmt.Sprintf("    was: %s", truncate(baseline[c.BaselineIndex-1].Thought, 80)))
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}},
+		StructuredContent: diff,
+	}, nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRestoreSnapshotRequiresConfirmForLocked(t *testing.T) {
+	tool := NewThinkTool(Config{}, nil)
+	invoke(t, tool.CreateSnapshot, SnapshotInput{Name: "empty"})
+	invoke(t, tool.Think, ThinkInput{Thought: "protected"})
+	invoke(t, tool.LockThought, LockThoughtInput{Index: 1})
+
+	_, err := tool.RestoreSnapshot(context.Background(), nil, &mcp.CallToolParamsFor[RestoreSnapshotInput]{Arguments: RestoreSnapshotInput{Name: "empty"}})
+	var te *ToolError
+	if !errors.As(err, &te) || te.Code != CodeLocked {
+		t.Fatalf("restore_snapshot over a locked thought returned %v, want a %s error", err, CodeLocked)
+	}
+	if len(tool.thoughts) != 1 {
+		t.Fatalf("a refused restore_snapshot left %d thought(s), want 1", len(tool.thoughts))
+	}
+
+	invoke(t, tool.ClearThoughts, ClearThoughtsInput{Confirm: true})
+	invoke(t, tool.RestoreSnapshot, RestoreSnapshotInput{Name: "empty", Confirm: true})
+	if tool.lastCleared != nil {
+		t.Errorf("restore_snapshot kept %d cleared thought(s) for undo, want them dropped", len(tool.lastCleared))
+	}
+}
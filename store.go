@@ -0,0 +1,287 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// store is the on-disk representation of the thought log.
+type store struct {
+	Goal      string              `json:"goal,omitempty"`
+	Thoughts  []ThoughtItem       `json:"thoughts"`
+	Snapshots map[string]Snapshot `json:"snapshots,omitempty"`
+	Shares    map[string]Share    `json:"shares,omitempty"`
+}
+
+// load reads the thoughts from the persistence file. A missing file is not an
+// error, the log simply starts empty.
+func (t *ThinkTool) load() error {
+	if t.cfg.Persist == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(t.cfg.Persist)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read persistence file: %w", err)
+	}
+
+	var s store
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("failed to parse persistence file %s: %w", t.cfg.Persist, err)
+	}
+	// Stores written before thoughts had IDs get them assigned on load.
+	for i := range s.Thoughts {
+		if s.Thoughts[i].ID == "" {
+			s.Thoughts[i].ID = newID()
+		}
+	}
+	t.goal = s.Goal
+	t.thoughts = s.Thoughts
+	t.snapshots = s.Snapshots
+	t.shares = s.Shares
+	t.audited = cloneThoughts(s.Thoughts)
+	t.relevance = nil
+	return t.seedJournal()
+}
+
+// verify reports the first problem of the stored thoughts, or nil.
+func (s *store) verify() error {
+	ids := map[string]int{}
+	for i, thought := range s.Thoughts {
+		if strings.TrimSpace(thought.Thought) == "" {
+			return fmt.Errorf("thought #%d has no text", i+1)
+		}
+		if _, err := time.Parse(time.RFC3339, thought.CreatedAt); err != nil {
+			return fmt.Errorf("thought #%d has an invalid timestamp %q", i+1, thought.CreatedAt)
+		}
+		if thought.ID == "" {
+			continue
+		}
+		if j, ok := ids[thought.ID]; ok {
+			return fmt.Errorf("thoughts #%d and #%d share the ID %q", j, i+1, thought.ID)
+		}
+		ids[thought.ID] = i + 1
+	}
+	return nil
+}
+
+// VerifyStore is a tool that checks that the persistence file can be loaded
+// and is consistent. It does not modify the file or the in-memory log.
+func (t *ThinkTool) VerifyStore(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	dirty := t.dirty
+	t.mu.RUnlock()
+
+	if t.cfg.Persist == "" {
+		return nil, toolError(CodeNotFound, "persistence is disabled. Start the server with -persist to use a store.")
+	}
+
+	b, err := os.ReadFile(t.cfg.Persist)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "ok: the store has not been written yet."}}}, nil
+	}
+	if err != nil {
+		return nil, toolError(CodeInternal, "failed to read persistence file: %v", err)
+	}
+
+	var s store
+	if err := json.Unmarshal(b, &s); err != nil {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("problem: the store cannot be parsed: %v", err)}}}, nil
+	}
+	if err := s.verify(); err != nil {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("problem: %v", err)}}}, nil
+	}
+
+	text := fmt.Sprintf("ok: %d thought(s) and %d snapshot(s) stored.", len(s.Thoughts), len(s.Snapshots))
+	if dirty {
+		text += " Some recent changes have not been flushed to disk yet."
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+}
+
+// durable returns the thoughts without the scratch thoughts recorded while
+// set_persistence was off, with the links between the remaining ones
+// renumbered and links to scratch thoughts dropped. It returns thoughts itself
+// if there are no scratch thoughts.
+func durable(thoughts []ThoughtItem) []ThoughtItem {
+	if !slices.ContainsFunc(thoughts, func(item ThoughtItem) bool { return item.scratch }) {
+		return thoughts
+	}
+	kept := []ThoughtItem{}
+	renumbered := map[int]int{}
+	for i, item := range thoughts {
+		if !item.scratch {
+			renumbered[i+1] = len(kept) + 1
+			kept = append(kept, item.clone())
+		}
+	}
+	for i := range kept {
+		kept[i].relink(func(index int) int { return renumbered[index] })
+	}
+	return kept
+}
+
+// save writes the thoughts to the persistence file, leaving out scratch
+// thoughts, also those in snapshots and shares. It must be called with t.mu
+// held. The file is replaced atomically so that a failed write never leaves a
+// truncated store behind.
+func (t *ThinkTool) save() error {
+	if t.cfg.Persist == "" {
+		return nil
+	}
+
+	s := store{Goal: t.goal, Thoughts: durable(t.thoughts)}
+	if len(t.snapshots) > 0 {
+		s.Snapshots = map[string]Snapshot{}
+		for name, snapshot := range t.snapshots {
+			snapshot.Thoughts = durable(snapshot.Thoughts)
+			s.Snapshots[name] = snapshot
+		}
+	}
+	if len(t.shares) > 0 {
+		s.Shares = map[string]Share{}
+		for id, share := range t.shares {
+			share.Thoughts = durable(share.Thoughts)
+			s.Shares[id] = share
+		}
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode thoughts: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(t.cfg.Persist), filepath.Base(t.cfg.Persist)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write thoughts: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync thoughts: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(f.Name(), t.cfg.Persist); err != nil {
+		return fmt.Errorf("failed to replace persistence file: %w", err)
+	}
+	return nil
+}
+
+// persist saves the thoughts and turns a failure into a warning that can be
+// appended to a tool response. The in-memory log is never rolled back: the
+// caller's change has happened, it just isn't durable yet.
+//
+// With -flush-interval the write is deferred to the next flush and persist
+// only marks the log as dirty; write failures are then logged by flush.
+//
+// The journal, if any, is written first. Scratch thoughts are kept out of
+// both, see set_persistence. Since every change of the log ends here, it
+// also drops the cached index of get_relevant_thoughts and adds the change to
+// the audit log, attributed to sess, which is nil for changes not made by a
+// tool call.
+func (t *ThinkTool) persist(sess *mcp.ServerSession) string {
+	t.relevance = nil
+	t.recordAudit(sess)
+	warning := ""
+	if err := t.syncJournal(); err != nil {
+		slog.Error("failed to write journal", slog.String("path", t.cfg.Journal), slog.Any("error", err))
+		warning = fmt.Sprintf("\nWarning: the change could not be written to the journal (%v).", err)
+	}
+	if t.cfg.FlushInterval > 0 && t.cfg.Persist != "" {
+		t.dirty = true
+		return warning
+	}
+	if err := t.save(); err != nil {
+		slog.Error("failed to persist thoughts", slog.String("path", t.cfg.Persist), slog.Any("error", err))
+		return warning + fmt.Sprintf("\nWarning: the change was applied in memory but could not be saved to disk (%v). Retry later or check the persistence file.", err)
+	}
+	return warning
+}
+
+// flush writes pending changes to the persistence file.
+func (t *ThinkTool) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.dirty {
+		return
+	}
+	if err := t.save(); err != nil {
+		slog.Error("failed to flush thoughts", slog.String("path", t.cfg.Persist), slog.Any("error", err))
+		return
+	}
+	t.dirty = false
+}
+
+// flushEvery flushes pending changes every interval until ctx is done. It is
+// a no-op if interval is not positive.
+func (t *ThinkTool) flushEvery(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type SetPersistenceInput struct {
+	Enabled bool `json:"enabled" jsonschema:"whether the thoughts this session records are written to disk"`
+}
+
+// SetPersistence is a tool that disables or re-enables persistence for the
+// current session, e.g. for scratch thoughts that should not be kept. While
+// disabled, the thoughts the session records are scratch thoughts: they are
+// part of the shared log, but never written to the persistence file or the
+// journal, and are lost on clear or restart. They stay scratch thoughts after
+// re-enabling. Other sessions are not affected, and changes of the session to
+// other thoughts, such as edits or removals, are persisted as usual.
+func (t *ThinkTool) SetPersistence(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SetPersistenceInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cfg.Persist == "" && t.cfg.Journal == "" {
+		return nil, toolError(CodeNotFound, "persistence is disabled. Start the server with -persist or -journal to use a store.")
+	}
+	state := t.session(sess)
+	state.ephemeral = !params.Arguments.Enabled
+	if state.ephemeral {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "Persistence is disabled for this session: the thoughts it records are kept in memory only and lost on clear or restart."}}}, nil
+	}
+	text := "Persistence is enabled for this session: the thoughts it records are written to disk."
+	if n := len(t.thoughts) - len(durable(t.thoughts)); n > 0 {
+		text += fmt.Sprintf(" %d scratch thought(s) recorded while it was disabled stay in memory only.", n)
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// resultText returns the text of the first content of a tool result.
+func resultText(t *testing.T, res *mcp.CallToolResultFor[any]) string {
+	t.Helper()
+	if len(res.Content) == 0 {
+		t.Fatal("tool result has no content")
+	}
+	text, ok := res.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("first content of the tool result is %T, want *mcp.TextContent", res.Content[0])
+	}
+	return text.Text
+}
+
+func TestThinkWarnsWhenSaveFails(t *testing.T) {
+	// The directory of the persistence file does not exist, so every save
+	// fails, even when the tests run as root.
+	tool := NewThinkTool(Config{Persist: filepath.Join(t.TempDir(), "missing", "thoughts.json")}, nil)
+
+	res, err := tool.Think(context.Background(), nil, &mcp.CallToolParamsFor[ThinkInput]{Arguments: ThinkInput{Thought: "keep me"}})
+	if err != nil {
+		t.Fatalf("Think failed: %v", err)
+	}
+	if text := resultText(t, res); !strings.Contains(text, "could not be saved to disk") {
+		t.Errorf("Think result %q does not warn that the save failed", text)
+	}
+	if len(tool.thoughts) != 1 || tool.thoughts[0].Thought != "keep me" {
+		t.Errorf("thoughts in memory = %+v, want the recorded thought", tool.thoughts)
+	}
+}
+
+func TestScratchThoughtsAreNotPersisted(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Persist: filepath.Join(dir, "thoughts.json"), Journal: filepath.Join(dir, "journal.jsonl")}
+	tool := NewThinkTool(cfg, nil)
+	server := mcp.NewServer(&mcp.Implementation{Name: serverName}, nil)
+	addTool(server, &mcp.Tool{Name: "think"}, tool.Think)
+	addTool(server, &mcp.Tool{Name: "set_persistence"}, tool.SetPersistence)
+	scratch, other := connect(t, server), connect(t, server)
+
+	callText(t, scratch, "think", map[string]any{"thought": "durable"})
+	callText(t, scratch, "set_persistence", map[string]any{"enabled": false})
+	callText(t, scratch, "think", map[string]any{"thought": "scratch"})
+	callText(t, other, "think", map[string]any{"thought": "other session"})
+	if text := callText(t, scratch, "set_persistence", map[string]any{"enabled": true}); !strings.Contains(text, "1 scratch thought(s)") {
+		t.Errorf("set_persistence after re-enabling = %q, want it to report the scratch thought", text)
+	}
+	callText(t, scratch, "think", map[string]any{"thought": "durable again", "parent_index": 3})
+
+	if len(tool.thoughts) != 4 {
+		t.Fatalf("thoughts in memory = %d, want 4", len(tool.thoughts))
+	}
+	for name, path := range map[string]string{"persistence file": cfg.Persist, "journal": cfg.Journal} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read the %s: %v", name, err)
+		}
+		if strings.Contains(string(b), `"scratch"`) {
+			t.Errorf("the %s contains the scratch thought:\n%s", name, b)
+		}
+		if !strings.Contains(string(b), "other session") || !strings.Contains(string(b), "durable again") {
+			t.Errorf("the %s lacks thoughts recorded with persistence enabled:\n%s", name, b)
+		}
+	}
+
+	// After a restart, the scratch thought is gone and the link of the last
+	// thought follows it to its new position.
+	restarted := NewThinkTool(cfg, nil)
+	if err := restarted.load(); err != nil {
+		t.Fatalf("failed to load the persistence file: %v", err)
+	}
+	got := []string{}
+	for _, thought := range restarted.thoughts {
+		got = append(got, thought.Thought)
+	}
+	if want := []string{"durable", "other session", "durable again"}; !slices.Equal(got, want) {
+		t.Fatalf("thoughts after a restart = %q, want %q", got, want)
+	}
+	if parent := restarted.thoughts[2].ParentIndex; parent != 2 {
+		t.Errorf("parent of the last thought after a restart = %d, want 2", parent)
+	}
+}
+
+func TestJournalIsSeededFromExistingStore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Persist: filepath.Join(dir, "thoughts.json")}
+	tool := NewThinkTool(cfg, nil)
+	invoke(t, tool.Think, ThinkInput{Thought: "recorded before the journal"})
+
+	cfg.Journal = filepath.Join(dir, "journal.jsonl")
+	tool = NewThinkTool(cfg, nil)
+	if err := tool.load(); err != nil {
+		t.Fatalf("failed to load the store: %v", err)
+	}
+	invoke(t, tool.Think, ThinkInput{Thought: "recorded with the journal"})
+
+	if err := os.Remove(cfg.Persist); err != nil {
+		t.Fatalf("failed to delete the store: %v", err)
+	}
+	tool = NewThinkTool(cfg, nil)
+	if err := tool.load(); err != nil {
+		t.Fatalf("failed to load without a store: %v", err)
+	}
+	invoke(t, tool.RebuildFromJournal, RebuildFromJournalInput{})
+	got := []string{}
+	for _, thought := range tool.thoughts {
+		got = append(got, thought.Thought)
+	}
+	if want := []string{"recorded before the journal", "recorded with the journal"}; !slices.Equal(got, want) {
+		t.Errorf("thoughts rebuilt from the journal = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// appendTail appends the recorded thought at position i to the -tail-file
+// for watching with tail -f: one line with the time, index and text, with
+// further lines of the text indented. The file is opened for every thought
+// and never truncated or rotated by the server, so it grows until removed or
+// rotated externally; since it is reopened each time, moving it aside starts
+// a new file. Failures are logged and do not affect recording. It must be
+// called with t.mu held.
+func (t *ThinkTool) appendTail(i int) {
+	if t.cfg.TailFile == "" {
+		return
+	}
+	item := t.thoughts[i]
+	text := strings.ReplaceAll(strings.TrimRight(item.Thought, "\n"), "\n", "\n    ")
+	if item.Kind != "" {
+		text = "[" + item.Kind + "] " + text
+	}
+	line := fmt.Sprintf("%s #%d %s\n", item.CreatedAt, i+1, text)
+
+	f, err := os.OpenFile(t.cfg.TailFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, err = f.WriteString(line)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to write tail file", slog.String("path", t.cfg.TailFile), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenize splits text into lowercase words. A word is a maximal run of
+// letters and digits; everything else separates words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// levenshtein returns the edit distance between a and b in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// similarity returns how similar two words are, from 0 (nothing in common)
+// to 1 (equal), based on their edit distance.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	n := max(len([]rune(a)), len([]rune(b)))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(n)
+}
+
+// defaultStopwords are common English words that carry little meaning on
+// their own and are ignored when comparing thoughts.
+var defaultStopwords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "been", "but", "by", "can", "do", "for",
+	"from", "has", "have", "he", "i", "if", "in", "into", "is", "it", "its", "me", "my",
+	"of", "on", "or", "our", "she", "so", "that", "the", "their", "them", "then", "there",
+	"these", "they", "this", "to", "was", "we", "were", "what", "when", "which", "will",
+	"with", "would", "you", "your",
+}
+
+// stopwordSet returns the given stopwords as a set, or the default stopwords
+// if none are given.
+func stopwordSet(words []string) map[string]bool {
+	if len(words) == 0 {
+		words = defaultStopwords
+	}
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(strings.TrimSpace(w))] = true
+	}
+	return set
+}
+
+// termVector returns the bag of words of text, ignoring stopwords.
+func termVector(text string, stopwords map[string]bool) map[string]float64 {
+	v := map[string]float64{}
+	for _, w := range tokenize(text) {
+		if !stopwords[w] {
+			v[w]++
+		}
+	}
+	return v
+}
+
+// cosine returns the cosine similarity of two term vectors, from 0 (no terms
+// in common) to 1 (same direction). Terms are summed in sorted order so that
+// the result does not depend on map iteration order.
+func cosine(a, b map[string]float64) float64 {
+	dot, na, nb := 0.0, 0.0, 0.0
+	for _, w := range slices.Sorted(maps.Keys(a)) {
+		dot += a[w] * b[w]
+		na += a[w] * a[w]
+	}
+	for _, w := range slices.Sorted(maps.Keys(b)) {
+		nb += b[w] * b[w]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// truncate shortens text to at most n runes, marking a cut with "...".
+func truncate(text string, n int) string {
+	r := []rune(text)
+	if len(r) <= n {
+		return text
+	}
+	return string(r[:n]) + "..."
+}
+
+// estimateTokens roughly estimates the number of model tokens of text, using
+// the common rule of thumb of four characters per token.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// ansiEscape matches ANSI escape sequences: CSI sequences such as colors and
+// cursor movement (ESC [ ... final byte), OSC sequences such as window titles
+// and hyperlinks (ESC ] ... BEL or ESC \), and two-byte escapes (ESC and one
+// byte from @ to _).
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-_]`)
+
+// sanitize makes text safe to embed into later prompts and terminals. It
+//
+//   - removes ANSI escape sequences (see ansiEscape),
+//   - turns CR LF and lone CR into LF,
+//   - removes the remaining C0 control characters (U+0000 to U+001F) except
+//     tab and LF, DEL (U+007F) and the C1 control characters (U+0080 to
+//     U+009F),
+//   - removes invisible characters that can hide or reorder text: zero-width
+//     characters and marks (U+200B to U+200F), bidirectional embeddings and
+//     overrides (U+202A to U+202E), bidirectional isolates (U+2066 to U+2069),
+//     the word joiner (U+2060) and the byte order mark (U+FEFF).
+//
+// All other characters are left unchanged.
+func sanitize(text string) string {
+	text = ansiEscape.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n':
+			return r
+		case r < 0x20, r >= 0x7f && r <= 0x9f,
+			r >= 0x200b && r <= 0x200f,
+			r >= 0x202a && r <= 0x202e,
+			r >= 0x2066 && r <= 0x2069,
+			r == 0x2060, r == 0xfeff:
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// langStopwords are frequent function words of the languages written in
+// Latin script that detectLang tells apart.
+var langStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "for", "with", "this", "not", "are", "be"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "auf", "ich", "sich"},
+	"fr": {"le", "la", "les", "et", "est", "un", "une", "des", "pas", "que", "pour", "dans", "du", "ce"},
+	"es": {"el", "la", "los", "las", "y", "es", "un", "una", "que", "no", "por", "para", "con", "del"},
+	"it": {"il", "lo", "gli", "e", "è", "un", "una", "che", "non", "per", "con", "di", "del", "sono"},
+	"pt": {"o", "os", "as", "e", "é", "um", "uma", "que", "não", "para", "com", "do", "da", "em"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "van", "met", "op", "dat", "voor", "zijn", "ik", "te"},
+}
+
+// langMinLetters is the number of letters below which detectLang does not
+// guess.
+const langMinLetters = 10
+
+// detectLang guesses the language of text and returns its ISO 639-1 code,
+// or "und" if it is too short or cannot be determined.
+//
+// Text in a script used by a single language is labeled by the script:
+// Hiragana or Katakana as ja, other Han as zh, Hangul as ko, Greek as el,
+// Hebrew as he, Thai as th and Devanagari as hi. Cyrillic is labeled ru and
+// Arabic ar. Text in Latin script is labeled with the language of
+// langStopwords it shares the most words with. The result is deterministic.
+func detectLang(text string) string {
+	scripts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range []string{"Latin", "Han", "Hiragana", "Katakana", "Hangul", "Cyrillic", "Greek", "Arabic", "Hebrew", "Thai", "Devanagari"} {
+			if unicode.Is(unicode.Scripts[s], r) {
+				scripts[s]++
+				break
+			}
+		}
+	}
+	// A few CJK characters carry as much as a sentence in Latin script.
+	if letters < langMinLetters && scripts["Han"]+scripts["Hiragana"]+scripts["Katakana"]+scripts["Hangul"] < 2 {
+		return "und"
+	}
+
+	script, count := "", 0
+	for _, s := range slices.Sorted(maps.Keys(scripts)) {
+		if scripts[s] > count {
+			script, count = s, scripts[s]
+		}
+	}
+	switch {
+	case scripts["Hiragana"]+scripts["Katakana"] > 0:
+		return "ja"
+	case script == "Han":
+		return "zh"
+	case script == "Hangul":
+		return "ko"
+	case script == "Cyrillic":
+		return "ru"
+	case script == "Greek":
+		return "el"
+	case script == "Arabic":
+		return "ar"
+	case script == "Hebrew":
+		return "he"
+	case script == "Thai":
+		return "th"
+	case script == "Devanagari":
+		return "hi"
+	case script != "Latin":
+		return "und"
+	}
+
+	words := map[string]bool{}
+	for _, w := range tokenize(text) {
+		words[w] = true
+	}
+	best, bestHits, tie := "und", 0, false
+	for _, lang := range slices.Sorted(maps.Keys(langStopwords)) {
+		hits := 0
+		for _, w := range langStopwords[lang] {
+			if words[w] {
+				hits++
+			}
+		}
+		switch {
+		case hits > bestHits:
+			best, bestHits, tie = lang, hits, false
+		case hits == bestHits && hits > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return "und"
+	}
+	return best
+}
+
+// wrap word-wraps every line of text to at most width runes. Words longer
+// than width are put on a line of their own rather than split. Existing line
+// breaks are kept.
+func wrap(text string, width int) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		current, n := []string{}, 0
+		for _, word := range strings.Fields(line) {
+			w := utf8.RuneCountInString(word)
+			if len(current) > 0 && n+1+w > width {
+				lines = append(lines, strings.Join(current, " "))
+				current, n = nil, 0
+			}
+			if len(current) > 0 {
+				n++
+			}
+			current, n = append(current, word), n+w
+		}
+		lines = append(lines, strings.Join(current, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// flatten joins the lines of text with single spaces, dropping blank lines
+// and the whitespace around line breaks.
+func flatten(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// spaceRun matches runs of spaces and tabs.
+var spaceRun = regexp.MustCompile(`[ \t]+`)
+
+// normalizeWhitespace trims text, removes trailing whitespace from its lines,
+// collapses runs of spaces and tabs after the indentation of a line into a
+// single space and collapses consecutive blank lines into one. Indentation is
+// kept since it may structure lists or code. Normalizing a normalized text
+// does not change it.
+func normalizeWhitespace(text string) string {
+	lines := []string{}
+	blank := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		body := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(body)]
+		lines = append(lines, indent+spaceRun.ReplaceAllString(body, " "))
+	}
+	return strings.Join(lines, "\n")
+}
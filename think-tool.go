@@ -6,108 +6,2266 @@ package main
 
 import (
 	"context"
-	"errors"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// serverName is the name the server reports to clients.
+const serverName = "think-tool"
+
 func init() {
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
 }
 
-// ThoughtItem is a thought that the tool appends to the log items.
-type ThoughtItem struct {
-	Thought   string `json:"thought"`
-	CreatedAt string `json:"created_at"`
+// ThoughtItem is a thought that the tool appends to the log items.
+type ThoughtItem struct {
+	ID         string   `json:"id,omitempty"` // Stable identifier that survives renumbering
+	Thought    string   `json:"thought"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at,omitempty"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	RemindAt   string   `json:"remind_at,omitempty"` // Time from which the thought is due as a reminder
+	Tags       []string `json:"tags,omitempty"`
+	Category   string   `json:"category,omitempty"`
+	Kind       string   `json:"kind,omitempty"` // One of thoughtKinds, empty for a plain thought
+	Notes      []string `json:"notes,omitempty"`
+	References []string `json:"references,omitempty"` // URLs, file paths or issue numbers the thought refers to
+	Priority   int      `json:"priority,omitempty"`   // Higher is more important
+	Confidence *float64 `json:"confidence,omitempty"` // From 0 (speculation) to 1 (certain), nil if not given
+	Raw        string   `json:"raw,omitempty"`        // Text as submitted, only set if sanitizing or flattening changed it
+
+	ParentIndex int   `json:"parent_index,omitempty"` // 1-based index of the thought this one follows from, 0 if none
+	DependsOn   []int `json:"depends_on,omitempty"`   // 1-based indices of the thoughts that must be resolved before this one
+
+	Resolved   bool   `json:"resolved,omitempty"`
+	ResolvedAt string `json:"resolved_at,omitempty"`
+	Locked     bool   `json:"locked,omitempty"` // Protects the text and links of the thought from edits and removal
+
+	scratch bool // Recorded while set_persistence was off for its session, never written to disk
+}
+
+// formatThought renders the thought at index i of the log for display.
+func (t *ThinkTool) formatThought(i int, item ThoughtItem) string {
+	meta := []string{}
+	if item.Locked {
+		meta = append(meta, "locked")
+	}
+	if item.Resolved {
+		meta = append(meta, "resolved")
+	} else if item.RemindAt != "" {
+		if item.due(t.clock.Now()) {
+			meta = append(meta, "due")
+		} else {
+			meta = append(meta, "remind at "+item.RemindAt)
+		}
+	}
+	if item.ParentIndex > 0 {
+		meta = append(meta, fmt.Sprintf("follows #%d", item.ParentIndex))
+	}
+	if len(item.DependsOn) > 0 {
+		deps := []string{}
+		for _, d := range item.DependsOn {
+			deps = append(deps, fmt.Sprintf("#%d", d))
+		}
+		meta = append(meta, "depends on "+strings.Join(deps, ", "))
+	}
+	if item.Priority != 0 {
+		meta = append(meta, fmt.Sprintf("priority: %d", item.Priority))
+	}
+	if item.Confidence != nil {
+		meta = append(meta, fmt.Sprintf("confidence: %g", *item.Confidence))
+	}
+	if item.Category != "" {
+		meta = append(meta, "category: "+item.Category)
+	}
+	if len(item.Tags) > 0 {
+		meta = append(meta, "tags: "+strings.Join(item.Tags, ", "))
+	}
+
+	header := t.msg("thought.header", i+1, item.CreatedAt)
+	if len(meta) > 0 {
+		header += " (" + strings.Join(meta, "; ") + ")"
+	}
+	body := item.Thought
+	if item.Kind != "" {
+		body = "[" + item.Kind + "] " + body
+	}
+	text := fmt.Sprintf("%s:\n%s\n", header, body)
+	if len(item.References) > 0 {
+		text += fmt.Sprintf("  References: %s\n", strings.Join(item.References, ", "))
+	}
+	for _, note := range item.Notes {
+		text += fmt.Sprintf("  Note: %s\n", note)
+	}
+	return text
+}
+
+// newID returns a random identifier for a thought.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cleanTags trims and lowercases the tags and drops empty and duplicate
+// ones, keeping the first occurrence of each.
+func cleanTags(tags []string) []string {
+	cleaned := []string{}
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" && !slices.Contains(cleaned, tag) {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil
+	}
+	return cleaned
+}
+
+// issueReference matches issue references such as #42 or owner/repo#42.
+var issueReference = regexp.MustCompile(`^([\w.-]+/[\w.-]+)?#\d+$`)
+
+// validReference reports whether ref looks like a URL with a scheme and a
+// host or path, an issue number, or a file path, which must not contain
+// whitespace and must contain a slash or a dot.
+func validReference(ref string) bool {
+	if issueReference.MatchString(ref) {
+		return true
+	}
+	if u, err := url.Parse(ref); err == nil && u.Scheme != "" && (u.Host != "" || u.Path != "" || u.Opaque != "") {
+		return !strings.ContainsFunc(ref, unicode.IsSpace)
+	}
+	return !strings.ContainsFunc(ref, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) && strings.ContainsAny(ref, "/.")
+}
+
+// clone returns a deep copy of the thought.
+func (item ThoughtItem) clone() ThoughtItem {
+	item.Tags = slices.Clone(item.Tags)
+	item.Notes = slices.Clone(item.Notes)
+	item.References = slices.Clone(item.References)
+	item.DependsOn = slices.Clone(item.DependsOn)
+	if item.Confidence != nil {
+		confidence := *item.Confidence
+		item.Confidence = &confidence
+	}
+	return item
+}
+
+// relink rewrites the links of the thought to other thoughts. f maps the
+// 1-based index of a linked thought to its new index, or to 0 to drop the
+// link.
+func (item *ThoughtItem) relink(f func(index int) int) {
+	if item.ParentIndex > 0 {
+		item.ParentIndex = f(item.ParentIndex)
+	}
+	var deps []int
+	for _, d := range item.DependsOn {
+		if d = f(d); d > 0 {
+			deps = append(deps, d)
+		}
+	}
+	item.DependsOn = deps
+}
+
+// cloneThoughts returns a deep copy of the thoughts.
+func cloneThoughts(items []ThoughtItem) []ThoughtItem {
+	cloned := make([]ThoughtItem, len(items))
+	for i, item := range items {
+		cloned[i] = item.clone()
+	}
+	return cloned
+}
+
+// due reports whether the thought is an unresolved reminder whose time has
+// come at now.
+func (item ThoughtItem) due(now time.Time) bool {
+	remindAt, err := time.Parse(time.RFC3339, item.RemindAt)
+	return err == nil && !item.Resolved && !now.Before(remindAt)
+}
+
+// expired reports whether the thought has a TTL that elapsed before now.
+func (item ThoughtItem) expired(now time.Time) bool {
+	if item.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, item.ExpiresAt)
+	return err == nil && !now.Before(expiresAt)
+}
+
+// Clock tells the current time and runs timers. All time reads and timers of
+// the ThinkTool go through it so that time-dependent behavior can be tested
+// with a fake clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once d has elapsed, like time.AfterFunc.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer started by Clock.AfterFunc, see time.Timer.
+type Timer interface {
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// ThinkTool is a tool that allows to think about something. It appends a thought to the log items.
+type ThinkTool struct {
+	cfg     Config
+	clock   Clock
+	started time.Time     // When the tool was created, for the uptime of runtime_stats
+	server  *mcp.Server   // The server the tools are added to, nil if not served
+	heavy   chan struct{} // Semaphore for expensive tools, nil if unlimited
+
+	redactions []*regexp.Regexp     // Patterns of secrets redacted by get_redacted_thoughts
+	schema     *jsonschema.Resolved // Schema of -thought-schema, nil if thoughts are free text
+	render     *template.Template   // Template of -render-template, nil if not configured
+
+	latencies latencies
+
+	// Tools that only read the log take mu for reading so that slow exports
+	// and searches do not block each other, all others take it for writing.
+	mu       sync.RWMutex
+	thoughts []ThoughtItem // A lot of thoughts are needed to solve a problem
+	dirty    bool          // Whether there are changes not yet flushed to the persistence file
+
+	goal            string        // What the reasoning is for, empty if not set
+	lastCleared     []ThoughtItem // Thoughts removed by the last clear, until the next clear or thought
+	snapshots       map[string]Snapshot
+	shares          map[string]Share // Read-only copies of the log by share ID
+	journaled       []ThoughtItem    // The log as of the last journal entry
+	audit           auditLog         // Latest changes of the log for export_audit_log
+	audited         []ThoughtItem    // The log as of the last audit event
+	recordingPaused bool             // Whether think refuses to record thoughts
+	relevance       *tfidfIndex      // Index of get_relevant_thoughts, nil until built after the last change
+	relevanceMu     sync.Mutex       // Guards building relevance while t.mu is held for reading
+
+	sessions map[string]*sessionState // State of the sessions by ID, see session
+
+	idleWarn  time.Duration // Idle period after which a warning is logged, 0 if disabled
+	idleTimer Timer
+}
+
+// lock takes t.mu for writing if write is set and for reading otherwise, and
+// returns the function releasing it. It is for tools that only change the log
+// depending on their input, such as exports with and_clear.
+func (t *ThinkTool) lock(write bool) (unlock func()) {
+	if write {
+		t.mu.Lock()
+		return t.mu.Unlock
+	}
+	t.mu.RLock()
+	return t.mu.RUnlock
+}
+
+// NewThinkTool returns a ThinkTool with the given configuration. If clock is
+// nil, the real clock is used.
+func NewThinkTool(cfg Config, clock Clock) *ThinkTool {
+	if clock == nil {
+		clock = realClock{}
+	}
+	t := &ThinkTool{cfg: cfg, clock: clock, started: clock.Now(), redactions: slices.Clone(defaultRedactions)}
+	for _, pattern := range cfg.Redact {
+		t.redactions = append(t.redactions, regexp.MustCompile(pattern))
+	}
+	t.render = template.Must(parseRenderTemplate(cfg.RenderTemplate))
+	if cfg.MaxConcurrency > 0 {
+		t.heavy = make(chan struct{}, cfg.MaxConcurrency)
+	}
+	return t
+}
+
+// limited guards an expensive tool handler so that at most -max-concurrency
+// such calls run at once. Excess calls are rejected rather than queued so
+// that a burst of slow calls cannot pile up behind the mutex.
+func limited[In any](t *ThinkTool, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[any], error) {
+		if t.heavy == nil {
+			return h(ctx, sess, params)
+		}
+		select {
+		case t.heavy <- struct{}{}:
+			defer func() { <-t.heavy }()
+			return h(ctx, sess, params)
+		default:
+			return nil, toolError(CodeBusy, "server busy: too many expensive calls in progress, retry later")
+		}
+	}
+}
+
+type ThinkInput struct {
+	Thought  string   `json:"thought" jsonschema:"a thought to record"`
+	TTL      string   `json:"ttl,omitempty" jsonschema:"optional lifetime after which the thought expires, e.g. 30m; thoughts without a ttl never expire"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"optional tags to categorize the thought"`
+	Priority int      `json:"priority,omitempty" jsonschema:"optional priority, higher is more important"`
+	Category string   `json:"category,omitempty" jsonschema:"optional category of the thought, required if the server runs with -require-category"`
+	Kind     string   `json:"kind,omitempty" jsonschema:"optional kind of reasoning step: thought, action or observation"`
+
+	Confidence *float64 `json:"confidence,omitempty" jsonschema:"optional confidence in the thought from 0 (speculation) to 1 (certain)"`
+
+	References []string `json:"references,omitempty" jsonschema:"optional URLs, file paths or issue numbers such as #42 the thought refers to"`
+
+	ParentIndex int   `json:"parent_index,omitempty" jsonschema:"optional 1-based index of an earlier thought this one follows from; if the server runs with -auto-parent, defaults to the previous thought"`
+	DependsOn   []int `json:"depends_on,omitempty" jsonschema:"optional 1-based indices of earlier thoughts that must be resolved before this one"`
+
+	remindAfter time.Duration // Set by think_with_reminder
+}
+
+// thoughtKinds are the kinds of reasoning steps of ReAct-style loops.
+var thoughtKinds = []string{"thought", "action", "observation"}
+
+// checkCategory trims category and checks it against -require-category and
+// the allow-list of -categories.
+func (t *ThinkTool) checkCategory(category string) (string, error) {
+	category = strings.TrimSpace(category)
+	allowed := t.cfg.Categories
+	hint := ""
+	if len(allowed) > 0 {
+		hint = ", expected one of " + strings.Join(allowed, ", ")
+	}
+	switch {
+	case category == "" && t.cfg.RequireCategory:
+		return "", toolError(CodeInvalidInput, "a category is required%s", hint)
+	case category != "" && len(allowed) > 0 && !slices.Contains(allowed, category):
+		return "", toolError(CodeInvalidInput, "invalid category %q%s", category, hint)
+	}
+	return category, nil
+}
+
+// checkTags checks cleaned tags against the allow-list of -tag-allowlist and
+// reports all tags outside of it at once.
+func (t *ThinkTool) checkTags(tags []string) error {
+	allowed := cleanTags(t.cfg.TagAllowlist)
+	if len(allowed) == 0 {
+		return nil
+	}
+	rejected := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(allowed, tag) {
+			rejected = append(rejected, fmt.Sprintf("%q", tag))
+		}
+	}
+	if len(rejected) > 0 {
+		return toolError(CodeInvalidInput, "tag(s) %s not allowed, expected any of %s", strings.Join(rejected, ", "), strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// tagEvictions checks a thought with the given cleaned tags against
+// -per-tag-limit. It returns the positions of the oldest unlocked thoughts to
+// evict so that no tag exceeds the limit once the thought is added, or an
+// error if the mode is reject or only locked thoughts could be evicted. It
+// must be called with t.mu held.
+func (t *ThinkTool) tagEvictions(tags []string) ([]int, error) {
+	limit := t.cfg.PerTagLimit
+	if limit <= 0 {
+		return nil, nil
+	}
+	evicted := map[int]bool{}
+	for _, tag := range tags {
+		holders := []int{}
+		for i, thought := range t.thoughts {
+			if !evicted[i] && slices.Contains(thought.Tags, tag) {
+				holders = append(holders, i)
+			}
+		}
+		if len(holders) < limit {
+			continue
+		}
+		if t.cfg.PerTagMode == perTagReject {
+			return nil, toolError(CodeBudgetExceeded, "tag %q already has %d thought(s), the limit per tag is %d. Remove or retag some of them first.", tag, len(holders), limit)
+		}
+		excess := len(holders) - limit + 1
+		for _, i := range holders {
+			if excess == 0 {
+				break
+			}
+			if !t.thoughts[i].Locked {
+				evicted[i] = true
+				excess--
+			}
+		}
+		if excess > 0 {
+			return nil, toolError(CodeLocked, "tag %q already has %d thought(s), the limit per tag is %d, and too many of them are locked to evict. Unlock some of them first.", tag, len(holders), limit)
+		}
+	}
+	return slices.Sorted(maps.Keys(evicted)), nil
+}
+
+// Think is a tool that allows to think about something. It appends a thought to the log items.
+func (t *ThinkTool) Think(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThinkInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.record(sess, params.Arguments)
+}
+
+// record validates in and appends it to the log as a thought recorded by
+// sess. It must be called with t.mu held.
+func (t *ThinkTool) record(sess *mcp.ServerSession, in ThinkInput) (*mcp.CallToolResultFor[any], error) {
+	thought := in.Thought
+	raw := ""
+	if t.cfg.Sanitize {
+		if clean := sanitize(thought); clean != thought {
+			thought, raw = clean, thought
+		}
+	}
+	if len(thought) == 0 {
+		return nil, toolError(CodeEmptyInput, "%s", t.msg("error.empty_thought"))
+	}
+	note := ""
+	if t.cfg.SingleLine != "" && strings.Contains(thought, "\n") {
+		// Leading and trailing line breaks are dropped silently.
+		multiline := strings.Contains(strings.TrimSpace(thought), "\n")
+		if multiline && t.cfg.SingleLine == singleLineReject {
+			return nil, toolError(CodeInvalidInput, "the thought spans several lines, but only single-line thoughts are accepted. Put it on one line or record several thoughts.")
+		}
+		if multiline {
+			note = "\nNote: the thought was flattened to a single line."
+		}
+		if raw == "" {
+			raw = thought
+		}
+		if thought = flatten(thought); thought == "" {
+			return nil, toolError(CodeEmptyInput, "%s", t.msg("error.empty_thought"))
+		}
+	}
+	if t.recordingPaused {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: t.msg("thought.paused", tidyThought(thought))}}}, nil
+	}
+	if err := t.checkSchema(thought); err != nil {
+		return nil, err
+	}
+	category, err := t.checkCategory(in.Category)
+	if err != nil {
+		return nil, err
+	}
+	tags := cleanTags(in.Tags)
+	if err := t.checkTags(tags); err != nil {
+		return nil, err
+	}
+	evict, err := t.tagEvictions(tags)
+	if err != nil {
+		return nil, err
+	}
+	if in.Kind != "" && !slices.Contains(thoughtKinds, in.Kind) {
+		return nil, toolError(CodeInvalidInput, "invalid kind %q, expected one of %s", in.Kind, strings.Join(thoughtKinds, ", "))
+	}
+	if c := in.Confidence; c != nil && (*c < 0 || *c > 1) {
+		return nil, toolError(CodeOutOfRange, "confidence must be between 0 and 1, got %g", *c)
+	}
+	tokens := estimateTokens(thought)
+	state := t.session(sess)
+	if budget := t.cfg.SessionTokenBudget; budget > 0 && state.tokens+tokens > budget {
+		return nil, toolError(CodeBudgetExceeded, "the thought (about %d tokens) would exceed the session token budget of %d, %d tokens are already used. Summarize the thoughts so far and clear them with clear_thoughts to continue.", tokens, budget, state.tokens)
+	}
+
+	now := t.clock.Now()
+	item := ThoughtItem{
+		ID:         newID(),
+		Thought:    thought,
+		CreatedAt:  now.Format(time.RFC3339),
+		Tags:       tags,
+		Category:   category,
+		Kind:       in.Kind,
+		Priority:   in.Priority,
+		Confidence: in.Confidence,
+		Raw:        raw,
+		scratch:    state.ephemeral,
+	}
+	parent := in.ParentIndex
+	if parent == 0 && t.cfg.AutoParent {
+		parent = len(t.thoughts)
+	}
+	if parent != 0 {
+		if _, err := t.thoughtAt(parent); err != nil {
+			return nil, toolError(CodeOutOfRange, "invalid parent_index: %v", err)
+		}
+		item.ParentIndex = parent
+	}
+	deps, err := t.checkDependencies(len(t.thoughts)+1, in.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+	item.DependsOn = deps
+	for _, ref := range in.References {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		if !validReference(ref) {
+			return nil, toolError(CodeInvalidInput, "invalid reference %q, expected a URL such as https://example.com/x, a file path such as cmd/main.go or an issue number such as #42", ref)
+		}
+		item.References = append(item.References, ref)
+	}
+	if ttl := in.TTL; ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return nil, toolError(CodeInvalidInput, "invalid ttl %q, expected a positive duration such as 30m", ttl)
+		}
+		item.ExpiresAt = now.Add(d).Format(time.RFC3339)
+	}
+	if in.remindAfter > 0 {
+		item.RemindAt = now.Add(in.remindAfter).Format(time.RFC3339)
+	}
+	t.thoughts = append(t.thoughts, item)
+	if len(evict) > 0 {
+		order, evicted := []int{}, []string{}
+		for i := range t.thoughts {
+			if slices.Contains(evict, i) {
+				evicted = append(evicted, fmt.Sprintf("#%d", i+1))
+			} else {
+				order = append(order, i)
+			}
+		}
+		t.rearrange(order)
+		note += fmt.Sprintf("\nEvicted the oldest thought(s) %s to stay within the limit of %d thought(s) per tag.", strings.Join(evicted, ", "), t.cfg.PerTagLimit)
+	}
+	t.appendTail(len(t.thoughts) - 1)
+	state.tokens += tokens
+	t.lastCleared = nil
+	t.resetIdle()
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: t.msg("thought.recorded", tidyThought(thought)) + note + warning}}}, nil
+}
+
+type RecordDecisionInput struct {
+	Context      string `json:"context,omitempty" jsonschema:"the situation and forces that call for a decision"`
+	Decision     string `json:"decision" jsonschema:"what was decided"`
+	Consequences string `json:"consequences,omitempty" jsonschema:"what becomes easier or harder because of the decision"`
+	Category     string `json:"category,omitempty" jsonschema:"optional category of the decision, required if the server runs with -require-category"`
+}
+
+// RecordDecision is a tool that records a decision as a thought tagged
+// decision, whose text lists the context, decision and consequences on
+// labeled lines.
+func (t *ThinkTool) RecordDecision(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[RecordDecisionInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	if strings.TrimSpace(args.Decision) == "" {
+		return nil, toolError(CodeEmptyInput, "no decision provided")
+	}
+	lines := []string{}
+	for _, field := range []struct{ label, text string }{
+		{"Context", args.Context},
+		{"Decision", args.Decision},
+		{"Consequences", args.Consequences},
+	} {
+		if text := strings.TrimSpace(field.text); text != "" {
+			lines = append(lines, field.label+": "+text)
+		}
+	}
+	return t.record(sess, ThinkInput{Thought: strings.Join(lines, "\n"), Tags: []string{"decision"}, Category: args.Category})
+}
+
+type GetThoughtsByKindInput struct {
+	Kind string `json:"kind" jsonschema:"the kind of reasoning step: thought, action or observation"`
+}
+
+// GetThoughtsByKind is a tool that returns the thoughts recorded with a kind.
+func (t *ThinkTool) GetThoughtsByKind(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsByKindInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	kind := params.Arguments.Kind
+	if !slices.Contains(thoughtKinds, kind) {
+		return nil, toolError(CodeInvalidInput, "invalid kind %q, expected one of %s", kind, strings.Join(thoughtKinds, ", "))
+	}
+
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if thought.Kind == kind {
+			thoughts = append(thoughts, t.formatThought(i, thought))
+		}
+	}
+	if len(thoughts) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No thoughts of kind %s.", kind)}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
+}
+
+type ThinkUnlessExistsInput struct {
+	Thought    string   `json:"thought" jsonschema:"a thought to record"`
+	GuardQuery string   `json:"guard_query" jsonschema:"the text that no recorded thought may contain for the thought to be recorded, matched case-insensitively as a substring"`
+	Tags       []string `json:"tags,omitempty" jsonschema:"optional tags to categorize the thought"`
+	Category   string   `json:"category,omitempty" jsonschema:"optional category of the thought, required if the server runs with -require-category"`
+}
+
+// ThinkUnlessExists is a tool that records a thought like Think unless a
+// recorded thought already contains the guard query, in which case the first
+// such thought is returned instead. The result tells which branch was taken.
+func (t *ThinkTool) ThinkUnlessExists(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThinkUnlessExistsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	guard := normalize(args.GuardQuery)
+	if guard == "" {
+		return nil, toolError(CodeEmptyInput, "no guard_query provided")
+	}
+	for i, thought := range t.thoughts {
+		if strings.Contains(normalize(thought.Thought), guard) {
+			return &mcp.CallToolResultFor[any]{
+				Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Not recorded: thought #%d already matches the guard.\n\n%s", i+1, t.formatThought(i, thought))}},
+				StructuredContent: map[string]any{"recorded": false, "index": i + 1},
+			}, nil
+		}
+	}
+
+	res, err := t.record(sess, ThinkInput{Thought: args.Thought, Tags: args.Tags, Category: args.Category})
+	if err != nil {
+		return nil, err
+	}
+	// Recording may still be paused.
+	if t.recordingPaused {
+		return res, nil
+	}
+	if c, ok := res.Content[0].(*mcp.TextContent); ok {
+		c.Text = "Recorded: no thought matches the guard.\n" + c.Text
+	}
+	res.StructuredContent = map[string]any{"recorded": true, "index": len(t.thoughts)}
+	return res, nil
+}
+
+type ThinkWithReminderInput struct {
+	Thought     string   `json:"thought" jsonschema:"a thought to record"`
+	RemindAfter string   `json:"remind_after" jsonschema:"the period after which the thought is due, e.g. 30m or 2h"`
+	Tags        []string `json:"tags,omitempty" jsonschema:"optional tags to categorize the thought"`
+	Priority    int      `json:"priority,omitempty" jsonschema:"optional priority, higher is more important"`
+	Category    string   `json:"category,omitempty" jsonschema:"optional category of the thought, required if the server runs with -require-category"`
+}
+
+// ThinkWithReminder is a tool that records a thought like Think that becomes
+// due after a period, to follow up on it later with get_due_reminders.
+func (t *ThinkTool) ThinkWithReminder(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThinkWithReminderInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	d, err := time.ParseDuration(args.RemindAfter)
+	if err != nil || d <= 0 {
+		return nil, toolError(CodeInvalidInput, "invalid remind_after %q, expected a positive duration such as 30m", args.RemindAfter)
+	}
+	return t.record(sess, ThinkInput{Thought: args.Thought, Tags: args.Tags, Priority: args.Priority, Category: args.Category, remindAfter: d})
+}
+
+// GetDueReminders is a tool that returns the reminders recorded with
+// think_with_reminder that are due. Resolving a thought dismisses its
+// reminder.
+func (t *ThinkTool) GetDueReminders(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := t.clock.Now()
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if thought.due(now) && !thought.expired(now) {
+			thoughts = append(thoughts, t.formatThought(i, thought))
+		}
+	}
+	if len(thoughts) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No reminders are due."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
+}
+
+type GetThoughtsInput struct {
+	MinLength   int  `json:"min_length,omitempty" jsonschema:"only return thoughts with at least this many characters, 0 means no lower bound"`
+	MaxLength   int  `json:"max_length,omitempty" jsonschema:"only return thoughts with at most this many characters, 0 means no upper bound"`
+	AfterIndex  int  `json:"after_index,omitempty" jsonschema:"only return thoughts with an index greater than this, e.g. the latest_index of a previous call"`
+	IncludeLang bool `json:"include_lang,omitempty" jsonschema:"label each thought with its detected language code, e.g. en, or und if unknown"`
+}
+
+// GetThoughtsResult is the structured result of the get_thoughts tool.
+type GetThoughtsResult struct {
+	LatestIndex int `json:"latest_index"` // Index of the most recent thought, to be passed as after_index
+}
+
+// GetThoughts is a tool that returns the thoughts recorded so far.
+func (t *ThinkTool) GetThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	if args.MinLength < 0 || args.MaxLength < 0 {
+		return nil, toolError(CodeOutOfRange, "min_length and max_length must not be negative")
+	}
+	if args.MaxLength > 0 && args.MaxLength < args.MinLength {
+		return nil, toolError(CodeOutOfRange, "max_length %d is smaller than min_length %d", args.MaxLength, args.MinLength)
+	}
+
+	// Incremental fetches report an empty result rather than an error so that
+	// polling clients can simply advance their cursor.
+	incremental := args.AfterIndex != 0
+	result := GetThoughtsResult{LatestIndex: len(t.thoughts)}
+	if len(t.thoughts) == 0 && !incremental {
+		return nil, t.errNoThoughts()
+	}
+
+	now := t.clock.Now()
+	thoughts := []string{}
+	live := 0
+	for i, thought := range t.thoughts {
+		if thought.expired(now) {
+			continue
+		}
+		live++
+		if i+1 <= args.AfterIndex {
+			continue
+		}
+		n := utf8.RuneCountInString(thought.Thought)
+		if n < args.MinLength || (args.MaxLength > 0 && n > args.MaxLength) {
+			continue
+		}
+		text := t.formatThought(i, thought)
+		if args.IncludeLang {
+			text += fmt.Sprintf("  Language: %s\n", detectLang(thought.Thought))
+		}
+		thoughts = append(thoughts, text)
+	}
+	if live == 0 && !incremental {
+		return nil, t.errNoThoughts()
+	}
+	if len(thoughts) == 0 {
+		text := "No thoughts match the given filters."
+		if incremental {
+			text = fmt.Sprintf("No new thoughts. The latest index is %d.", result.LatestIndex)
+		}
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}, StructuredContent: result}, nil
+	}
+
+	if incremental {
+		thoughts = append(thoughts, fmt.Sprintf("The latest index is %d.", result.LatestIndex))
+	}
+	if t.goal != "" {
+		thoughts = append([]string{fmt.Sprintf("Goal: %s\n", t.goal)}, thoughts...)
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n"), StructuredContent: result}, nil
+}
+
+// frames joins parts with sep into a single text content, or into several
+// content blocks of at most -max-frame-bytes each if the joined text would be
+// larger, so that a huge log does not have to go out as one enormous frame.
+// Parts are never split; a part larger than the limit gets its own block.
+func (t *ThinkTool) frames(parts []string, sep string) []mcp.Content {
+	max := t.cfg.MaxFrameBytes
+	if max <= 0 || len(strings.Join(parts, sep)) <= max {
+		return []mcp.Content{&mcp.TextContent{Text: strings.Join(parts, sep)}}
+	}
+
+	blocks := []string{}
+	var b strings.Builder
+	for _, part := range parts {
+		if b.Len() > 0 && b.Len()+len(sep)+len(part) > max {
+			blocks = append(blocks, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(part)
+	}
+	blocks = append(blocks, b.String())
+
+	content := []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Note: the output is large and was split into %d blocks. Consider fetching fewer thoughts at once, e.g. with after_index or export_json with max_bytes.", len(blocks))}}
+	for _, block := range blocks {
+		content = append(content, &mcp.TextContent{Text: block})
+	}
+	return content
+}
+
+// headlineWords is the default maximum number of words of a headline.
+const headlineWords = 8
+
+// headline derives a short title for a thought: the first n words of its
+// first non-blank line, marked with "..." if anything was cut.
+func headline(text string, n int) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	words := strings.Fields(lines[0])
+	if len(words) > n {
+		return strings.Join(words[:n], " ") + "..."
+	}
+	title := strings.Join(words, " ")
+	if len(lines) > 1 {
+		title += "..."
+	}
+	return title
+}
+
+type GetWrappedThoughtsInput struct {
+	Width int `json:"width,omitempty" jsonschema:"the column width to wrap the text of each thought at, 0 to not wrap"`
+}
+
+// GetWrappedThoughts is a tool that returns the thoughts recorded so far with
+// their text word-wrapped to a column width, for display in fixed-width UIs.
+func (t *ThinkTool) GetWrappedThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetWrappedThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	width := params.Arguments.Width
+	if width < 0 {
+		return nil, toolError(CodeOutOfRange, "width must not be negative, got %d", width)
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if width > 0 {
+			thought.Thought = wrap(thought.Thought, width)
+		}
+		thoughts = append(thoughts, t.formatThought(i, thought))
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(thoughts, "\n")}, nil
+}
+
+// GetThoughtsWithTOC is a tool that returns the thoughts recorded so far,
+// preceded by a table of contents with a short title per thought.
+func (t *ThinkTool) GetThoughtsWithTOC(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := t.clock.Now()
+	toc := []string{"Table of contents:"}
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if thought.expired(now) {
+			continue
+		}
+		toc = append(toc, fmt.Sprintf("  #%d %s", i+1, headline(thought.Thought, headlineWords)))
+		thoughts = append(thoughts, t.formatThought(i, thought))
+	}
+	if len(thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(append([]string{strings.Join(toc, "\n") + "\n"}, thoughts...), "\n")}, nil
+}
+
+type GetThoughtsPageInput struct {
+	AfterID string `json:"after_id,omitempty" jsonschema:"return the thoughts after the thought with this ID, i.e. the next_cursor of a previous call; empty starts at the first thought"`
+	Limit   int    `json:"limit,omitempty" jsonschema:"maximum number of thoughts per page, defaults to 20"`
+}
+
+// GetThoughtsPageResult is the structured result of the get_thoughts_page
+// tool.
+type GetThoughtsPageResult struct {
+	Thoughts   []ThoughtItem `json:"thoughts"`
+	NextCursor string        `json:"next_cursor,omitempty"` // ID of the last thought of the page, empty on the last page
+}
+
+// GetThoughtsPage is a tool that returns the thoughts one page at a time.
+// Pages are delimited by thought IDs rather than positions, so thoughts that
+// are added or removed between calls do not shift the following pages.
+func (t *ThinkTool) GetThoughtsPage(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtsPageInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	limit := args.Limit
+	if limit < 0 {
+		return nil, toolError(CodeOutOfRange, "limit must not be negative")
+	}
+	if limit == 0 {
+		limit = 20
+	}
+	start := 0
+	if args.AfterID != "" {
+		i := slices.IndexFunc(t.thoughts, func(item ThoughtItem) bool { return item.ID == args.AfterID })
+		if i < 0 {
+			return nil, toolError(CodeNotFound, "no thought with ID %q, it may have been removed. Start again without after_id.", args.AfterID)
+		}
+		start = i + 1
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	end := min(start+limit, len(t.thoughts))
+	result := GetThoughtsPageResult{Thoughts: cloneThoughts(t.thoughts[start:end])}
+	if end < len(t.thoughts) {
+		result.NextCursor = t.thoughts[end-1].ID
+	}
+	if start == end {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No more thoughts."}}, StructuredContent: result}, nil
+	}
+
+	parts := []string{}
+	for i := start; i < end; i++ {
+		parts = append(parts, t.formatThought(i, t.thoughts[i]))
+	}
+	if result.NextCursor != "" {
+		parts = append(parts, fmt.Sprintf("More thoughts follow. Pass after_id %q to get the next page.", result.NextCursor))
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(parts, "\n"), StructuredContent: result}, nil
+}
+
+// touchedAt returns when the thought was last created or modified.
+func (item ThoughtItem) touchedAt() time.Time {
+	created, _ := time.Parse(time.RFC3339, item.CreatedAt)
+	updated, err := time.Parse(time.RFC3339, item.UpdatedAt)
+	if err != nil || updated.Before(created) {
+		return created
+	}
+	return updated
+}
+
+type GetRecentlyModifiedInput struct {
+	Limit int `json:"limit,omitempty" jsonschema:"maximum number of thoughts to return, defaults to 10"`
+}
+
+// GetRecentlyModified is a tool that returns the thoughts that were most
+// recently recorded or modified, most recent first.
+func (t *ThinkTool) GetRecentlyModified(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetRecentlyModifiedInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	limit := params.Arguments.Limit
+	if limit < 0 {
+		return nil, toolError(CodeOutOfRange, "limit must not be negative")
+	}
+	if limit == 0 {
+		limit = 10
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	// Thoughts touched at the same second are ordered by position, later
+	// ones first.
+	order := make([]int, len(t.thoughts))
+	for i := range order {
+		order[i] = len(order) - 1 - i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return t.thoughts[b].touchedAt().Compare(t.thoughts[a].touchedAt())
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	thoughts := []string{}
+	for _, i := range order {
+		thoughts = append(thoughts, t.formatThought(i, t.thoughts[i]))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
+}
+
+type GetThoughtHeadlinesInput struct {
+	MaxWords int `json:"max_words,omitempty" jsonschema:"maximum number of words per headline, defaults to 8"`
+}
+
+// GetThoughtHeadlines is a tool that returns the first line of every thought
+// as a compact index of the log.
+func (t *ThinkTool) GetThoughtHeadlines(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtHeadlinesInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	n := params.Arguments.MaxWords
+	if n < 0 {
+		return nil, toolError(CodeOutOfRange, "max_words must not be negative")
+	}
+	if n == 0 {
+		n = headlineWords
+	}
+
+	now := t.clock.Now()
+	lines := []string{}
+	for i, thought := range t.thoughts {
+		if !thought.expired(now) {
+			lines = append(lines, fmt.Sprintf("#%d %s", i+1, headline(thought.Thought, n)))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, t.errNoThoughts()
+	}
+	return &mcp.CallToolResultFor[any]{Content: t.frames(lines, "\n")}, nil
+}
+
+type GetThoughtInput struct {
+	Index int `json:"index" jsonschema:"the 1-based index of the thought"`
+}
+
+// GetThought is a tool that returns a single thought.
+func (t *ThinkTool) GetThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	i, err := t.thoughtAt(params.Arguments.Index)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: t.formatThought(i, t.thoughts[i])}},
+		StructuredContent: t.thoughts[i].clone(),
+	}, nil
+}
+
+type ExpandThoughtInput struct {
+	Index   int    `json:"index,omitempty" jsonschema:"the 1-based index of the thought to expand"`
+	Preview string `json:"preview,omitempty" jsonschema:"a truncated preview of the thought as returned by another tool, including the trailing ..."`
+}
+
+// ExpandedThought is an entry of the structured result of expand_thought.
+type ExpandedThought struct {
+	Index   int    `json:"index"`
+	Thought string `json:"thought"`
+}
+
+// ExpandThought is a tool that returns the full text of a thought given its
+// index or a truncated preview of it. A preview matches every thought that
+// begins with it, ignoring differences in whitespace, so that the previews of
+// all tools can be expanded regardless of how they cut the text. All matches
+// are returned if the preview is ambiguous.
+func (t *ThinkTool) ExpandThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ExpandThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	args := params.Arguments
+	switch {
+	case args.Index == 0 && args.Preview == "":
+		return nil, toolError(CodeEmptyInput, "no index or preview provided")
+	case args.Index != 0 && args.Preview != "":
+		return nil, toolError(CodeInvalidInput, "index and preview are mutually exclusive")
+	}
+
+	matches := []int{}
+	if args.Index != 0 {
+		i, err := t.thoughtAt(args.Index)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, i)
+	} else {
+		normalize := func(text string) string { return strings.Join(strings.Fields(text), " ") }
+		prefix := normalize(strings.TrimSuffix(strings.TrimSuffix(args.Preview, "..."), "…"))
+		if prefix == "" {
+			return nil, toolError(CodeEmptyInput, "the preview is empty")
+		}
+		for i, thought := range t.thoughts {
+			if strings.HasPrefix(normalize(thought.Thought), prefix) {
+				matches = append(matches, i)
+			}
+		}
+		if len(matches) == 0 {
+			return nil, toolError(CodeNotFound, "no thought begins with %q", prefix)
+		}
+	}
+
+	thoughts := []string{}
+	expanded := []ExpandedThought{}
+	if len(matches) > 1 {
+		thoughts = append(thoughts, fmt.Sprintf("%d thoughts match the preview:", len(matches)))
+	}
+	for _, i := range matches {
+		thoughts = append(thoughts, t.formatThought(i, t.thoughts[i]))
+		expanded = append(expanded, ExpandedThought{Index: i + 1, Thought: t.thoughts[i].Thought})
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           t.frames(thoughts, "\n"),
+		StructuredContent: map[string]any{"matches": expanded},
+	}, nil
+}
+
+// GetThoughtsByDay is a tool that returns the thoughts recorded so far grouped by
+// the calendar date they were recorded on in the zone of -timezone, UTC by
+// default, regardless of the offset their timestamps were written with.
+func (t *ThinkTool) GetThoughtsByDay(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	loc, err := time.LoadLocation(t.cfg.Timezone)
+	if err != nil {
+		return nil, toolError(CodeInternal, "invalid timezone %q: %v", t.cfg.Timezone, err)
+	}
+	days := []string{}
+	byDay := map[string][]string{}
+	for i, thought := range t.thoughts {
+		createdAt, err := time.Parse(time.RFC3339, thought.CreatedAt)
+		if err != nil {
+			return nil, toolError(CodeInternal, "thought #%d has an invalid timestamp %q: %v", i+1, thought.CreatedAt, err)
+		}
+		day := createdAt.In(loc).Format(time.DateOnly)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], t.formatThought(i, thought))
+	}
+	sort.Strings(days)
+
+	sections := []string{}
+	for _, day := range days {
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", day, strings.Join(byDay[day], "\n")))
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(sections, "\n")}}}, nil
+}
+
+type ReplaceInThoughtsInput struct {
+	Find    string `json:"find" jsonschema:"the text to find, or a regular expression if regex is set"`
+	Replace string `json:"replace,omitempty" jsonschema:"the replacement text, may reference capture groups such as $1 if regex is set"`
+	Regex   bool   `json:"regex,omitempty" jsonschema:"treat find as a regular expression"`
+}
+
+// ReplaceInThoughts is a tool that replaces text across all recorded thoughts.
+func (t *ThinkTool) ReplaceInThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ReplaceInThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := params.Arguments
+	if len(args.Find) == 0 {
+		return nil, toolError(CodeEmptyInput, "no text to find provided")
+	}
+
+	var re *regexp.Regexp
+	if args.Regex {
+		var err error
+		re, err = regexp.Compile(args.Find)
+		if err != nil {
+			return nil, toolError(CodeInvalidInput, "invalid regular expression: %v", err)
+		}
+	}
+
+	changed, replacements, locked := 0, 0, 0
+	now := t.clock.Now().Format(time.RFC3339)
+	for i, thought := range t.thoughts {
+		if thought.Locked {
+			locked++
+			continue
+		}
+		var n int
+		var text string
+		if re != nil {
+			n = len(re.FindAllStringIndex(thought.Thought, -1))
+			text = re.ReplaceAllString(thought.Thought, args.Replace)
+		} else {
+			n = strings.Count(thought.Thought, args.Find)
+			text = strings.ReplaceAll(thought.Thought, args.Find, args.Replace)
+		}
+		if text == thought.Thought {
+			continue
+		}
+		changed++
+		replacements += n
+		t.thoughts[i].Thought = text
+		t.thoughts[i].UpdatedAt = now
+	}
+
+	warning := ""
+	if changed > 0 {
+		warning = t.persist(sess)
+	}
+	if locked > 0 {
+		warning = fmt.Sprintf(" Skipped %d locked thought(s).", locked) + warning
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Replaced %d occurrence(s) in %d thought(s).%s", replacements, changed, warning)}}}, nil
+}
+
+type TagMatchingInput struct {
+	Query string `json:"query" jsonschema:"the text to look for, matched case-insensitively as a substring of each thought"`
+	Tag   string `json:"tag" jsonschema:"the tag to add to every matching thought"`
+}
+
+// TagMatching is a tool that adds a tag to every thought containing a query.
+// Thoughts that already have the tag are left unchanged.
+func (t *ThinkTool) TagMatching(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[TagMatchingInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	query := normalize(params.Arguments.Query)
+	if query == "" {
+		return nil, toolError(CodeEmptyInput, "no query provided")
+	}
+	tag := strings.ToLower(strings.TrimSpace(params.Arguments.Tag))
+	if tag == "" {
+		return nil, toolError(CodeEmptyInput, "no tag provided")
+	}
+	if err := t.checkTags([]string{tag}); err != nil {
+		return nil, err
+	}
+
+	matched, tagged := 0, 0
+	now := t.clock.Now().Format(time.RFC3339)
+	for i, thought := range t.thoughts {
+		if !strings.Contains(normalize(thought.Thought), query) {
+			continue
+		}
+		matched++
+		if slices.Contains(thought.Tags, tag) {
+			continue
+		}
+		tagged++
+		t.thoughts[i].Tags = append(slices.Clone(thought.Tags), tag)
+		t.thoughts[i].UpdatedAt = now
+	}
+
+	if matched == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}}, nil
+	}
+	text := fmt.Sprintf("Tagged %d thought(s) with %q.", tagged, tag)
+	if matched > tagged {
+		text += fmt.Sprintf(" %d matching thought(s) already had the tag.", matched-tagged)
+	}
+	if tagged > 0 {
+		text += t.persist(sess)
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+}
+
+type ClearThoughtsInput struct {
+	Confirm  bool `json:"confirm,omitempty" jsonschema:"confirm clearing the log even though it contains locked thoughts"`
+	KeepGoal bool `json:"keep_goal,omitempty" jsonschema:"keep the goal set with set_goal, which is removed by default"`
 }
 
-// ThinkTool is a tool that allows to think about something. It appends a thought to the log items.
-type ThinkTool struct {
-	mu       sync.Mutex
-	thoughts []ThoughtItem // A lot of thoughts are needed to solve a problem
+// checkClear reports why the log cannot be cleared: it contains locked
+// thoughts and confirm is not set. It must be called with t.mu held.
+func (t *ThinkTool) checkClear(confirm bool) error {
+	if confirm {
+		return nil
+	}
+	locked := 0
+	for _, thought := range t.thoughts {
+		if thought.Locked {
+			locked++
+		}
+	}
+	if locked > 0 {
+		return toolError(CodeLocked, "the log contains %d locked thought(s). Set confirm to clear them as well.", locked)
+	}
+	return nil
 }
 
-type ThinkInput struct {
-	Thought string `json:"thought" jsonschema:"a thought to record"`
+func (t *ThinkTool) ClearThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ClearThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkClear(params.Arguments.Confirm); err != nil {
+		return nil, err
+	}
+
+	t.lastCleared = t.thoughts
+	t.thoughts = []ThoughtItem{}
+	t.resetSessionTokens()
+	if !params.Arguments.KeepGoal {
+		t.goal = ""
+	}
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: t.msg("thoughts.cleared") + warning}}}, nil
 }
 
-// Think is a tool that allows to think about something. It appends a thought to the log items.
-func (t *ThinkTool) Think(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ThinkInput]) (*mcp.CallToolResultFor[any], error) {
+type TrimToLastInput struct {
+	N int `json:"n" jsonschema:"the number of most recent thoughts to keep"`
+}
+
+// TrimToLast is a tool that removes all but the n most recent thoughts and
+// renumbers the remaining ones. Locked thoughts are kept regardless of their
+// position.
+func (t *ThinkTool) TrimToLast(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[TrimToLastInput]) (*mcp.CallToolResultFor[any], error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	thought := params.Arguments.Thought
-	if len(thought) == 0 {
-		return nil, errors.New("no thoughts provided")
+	n := params.Arguments.N
+	if n < 1 {
+		return nil, toolError(CodeOutOfRange, "n must be at least 1, got %d. Use clear_thoughts to remove all thoughts.", n)
+	}
+	if n >= len(t.thoughts) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("The log has %d thought(s), nothing to trim.", len(t.thoughts))}}}, nil
+	}
+
+	kept := []int{}
+	for i, thought := range t.thoughts {
+		if i >= len(t.thoughts)-n || thought.Locked {
+			kept = append(kept, i)
+		}
+	}
+	removed := len(t.thoughts) - len(kept)
+	t.rearrange(kept)
+	warning := t.persist(sess)
+
+	text := fmt.Sprintf("Removed %d thought(s), %d remain.", removed, len(t.thoughts))
+	if locked := len(t.thoughts) - n; locked > 0 {
+		text += fmt.Sprintf(" Kept %d older locked thought(s).", locked)
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text + warning}}}, nil
+}
+
+// CanonicalizeThoughts is a tool that normalizes the whitespace of the
+// thoughts (see normalizeWhitespace), removes the thoughts whose normalized
+// text duplicates an earlier one and renumbers the rest. Links to a removed
+// duplicate are moved to the thought it duplicates. Locked thoughts are
+// neither changed nor removed. Running it again changes nothing.
+func (t *ThinkTool) CanonicalizeThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	now := t.clock.Now().Format(time.RFC3339)
+	normalized := 0
+	first := map[string]int{} // Normalized text -> position of its first thought
+	original := map[int]int{} // Position of a removed duplicate -> position of the thought it duplicates
+	removed := []string{}
+	kept := []int{}
+	for i := range t.thoughts {
+		thought := &t.thoughts[i]
+		text := normalizeWhitespace(thought.Thought)
+		if !thought.Locked && text != thought.Thought {
+			thought.Thought = text
+			thought.UpdatedAt = now
+			normalized++
+		}
+		if j, ok := first[text]; ok && !thought.Locked {
+			original[i] = j
+			removed = append(removed, fmt.Sprintf("#%d (duplicate of #%d)", i+1, j+1))
+			continue
+		}
+		if _, ok := first[text]; !ok {
+			first[text] = i
+		}
+		kept = append(kept, i)
+	}
+
+	renumbered := make(map[int]int, len(t.thoughts)) // old 1-based index -> new 1-based index
+	for i, old := range kept {
+		renumbered[old+1] = i + 1
+	}
+	for dup, orig := range original {
+		renumbered[dup+1] = renumbered[orig+1]
+	}
+	thoughts := make([]ThoughtItem, len(kept))
+	for i, old := range kept {
+		item := t.thoughts[old]
+		item.relink(func(index int) int {
+			if n := renumbered[index]; n != i+1 {
+				return n
+			}
+			return 0
+		})
+		slices.Sort(item.DependsOn)
+		item.DependsOn = slices.Compact(item.DependsOn)
+		thoughts[i] = item
+	}
+	t.thoughts = thoughts
+
+	if normalized == 0 && len(removed) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "The log is already canonical."}}}, nil
+	}
+	warning := t.persist(sess)
+	text := fmt.Sprintf("Normalized the whitespace of %d thought(s) and removed %d duplicate(s), %d thought(s) remain.", normalized, len(removed), len(t.thoughts))
+	if len(removed) > 0 {
+		text += "\nRemoved: " + strings.Join(removed, ", ")
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text + warning}}}, nil
+}
+
+// thoughtAt converts the 1-based index of a thought as shown to the model
+// into a position in t.thoughts. It must be called with t.mu held.
+func (t *ThinkTool) thoughtAt(index int) (int, error) {
+	if len(t.thoughts) == 0 {
+		return 0, t.errNoThoughts()
+	}
+	if index < 1 || index > len(t.thoughts) {
+		return 0, toolError(CodeOutOfRange, "thought #%d does not exist, valid indices are 1 to %d", index, len(t.thoughts))
+	}
+	return index - 1, nil
+}
+
+// rearrange replaces the log with the thoughts at the given positions of the
+// current log, in that order, and rewrites the links between thoughts to the
+// new numbering. Links to thoughts that are no longer part of the log are
+// dropped. It must be called with t.mu held.
+func (t *ThinkTool) rearrange(order []int) {
+	renumbered := make(map[int]int, len(order)) // old 1-based index -> new 1-based index
+	for i, old := range order {
+		renumbered[old+1] = i + 1
+	}
+
+	thoughts := make([]ThoughtItem, len(order))
+	for i, old := range order {
+		item := t.thoughts[old]
+		item.relink(func(index int) int { return renumbered[index] })
+		thoughts[i] = item
+	}
+	t.thoughts = thoughts
+}
+
+type SortThoughtsInput struct {
+	By   string `json:"by" jsonschema:"the sort key: time, length, priority or alpha"`
+	Desc bool   `json:"desc,omitempty" jsonschema:"sort in descending order"`
+}
+
+// SortThoughts is a tool that permanently reorders the log and renumbers the
+// thoughts. The sort is stable, so thoughts with equal keys keep their
+// relative order.
+func (t *ThinkTool) SortThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[SortThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var cmp func(a, b ThoughtItem) int
+	switch params.Arguments.By {
+	case "time":
+		cmp = func(a, b ThoughtItem) int {
+			ta, _ := time.Parse(time.RFC3339, a.CreatedAt)
+			tb, _ := time.Parse(time.RFC3339, b.CreatedAt)
+			return ta.Compare(tb)
+		}
+	case "length":
+		cmp = func(a, b ThoughtItem) int {
+			return utf8.RuneCountInString(a.Thought) - utf8.RuneCountInString(b.Thought)
+		}
+	case "priority":
+		cmp = func(a, b ThoughtItem) int { return a.Priority - b.Priority }
+	case "alpha":
+		cmp = func(a, b ThoughtItem) int {
+			return strings.Compare(strings.ToLower(a.Thought), strings.ToLower(b.Thought))
+		}
+	default:
+		return nil, toolError(CodeInvalidInput, "unknown sort key %q, expected time, length, priority or alpha", params.Arguments.By)
+	}
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
 	}
 
-	t.thoughts = append(t.thoughts, ThoughtItem{
-		Thought:   thought,
-		CreatedAt: time.Now().Format(time.RFC3339),
+	order := make([]int, len(t.thoughts))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		c := cmp(t.thoughts[i], t.thoughts[j])
+		if params.Arguments.Desc {
+			c = -c
+		}
+		return c
 	})
-	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Thought: %s", tidyThought(thought))}}}, nil
+	t.rearrange(order)
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Sorted %d thought(s) by %s.%s", len(t.thoughts), params.Arguments.By, warning)}}}, nil
 }
 
-// GetThoughts is a tool that returns the thoughts recorded so far.
-func (t *ThinkTool) GetThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+type AnnotateThoughtInput struct {
+	Index int    `json:"index" jsonschema:"the 1-based index of the thought to annotate"`
+	Note  string `json:"note" jsonschema:"the note to attach, e.g. superseded by #7"`
+}
+
+// AnnotateThought is a tool that attaches a note to a thought without
+// changing the thought itself.
+func (t *ThinkTool) AnnotateThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[AnnotateThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	note := strings.TrimSpace(params.Arguments.Note)
+	if note == "" {
+		return nil, toolError(CodeEmptyInput, "no note provided")
+	}
+	i, err := t.thoughtAt(params.Arguments.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	t.thoughts[i].Notes = append(t.thoughts[i].Notes, note)
+	t.thoughts[i].UpdatedAt = t.clock.Now().Format(time.RFC3339)
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Annotated thought #%d.%s", i+1, warning)}}}, nil
+}
+
+type ResolveThoughtInput struct {
+	Index int `json:"index" jsonschema:"the 1-based index of the thought to mark as resolved"`
+}
+
+// ResolveThought is a tool that marks a thought as resolved, e.g. when the
+// thought describes a task that has been acted on.
+func (t *ThinkTool) ResolveThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ResolveThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i, err := t.thoughtAt(params.Arguments.Index)
+	if err != nil {
+		return nil, err
+	}
+	if t.thoughts[i].Resolved {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Thought #%d was already resolved at %s.", i+1, t.thoughts[i].ResolvedAt)}}}, nil
+	}
+
+	now := t.clock.Now().Format(time.RFC3339)
+	t.thoughts[i].Resolved = true
+	t.thoughts[i].ResolvedAt = now
+	t.thoughts[i].UpdatedAt = now
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Resolved thought #%d.%s", i+1, warning)}}}, nil
+}
+
+type ResolveMatchingInput struct {
+	Query string `json:"query" jsonschema:"the text to look for, matched case-insensitively as a substring of each thought"`
+}
+
+// ResolveMatching is a tool that resolves every unresolved thought containing
+// a query, e.g. to close all tasks of a finished subproblem at once. Matching
+// thoughts that are already resolved are skipped.
+func (t *ThinkTool) ResolveMatching(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[ResolveMatchingInput]) (*mcp.CallToolResultFor[any], error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	query := normalize(params.Arguments.Query)
+	if query == "" {
+		return nil, toolError(CodeEmptyInput, "no query provided")
+	}
+
+	matched, resolved := 0, []int{}
+	now := t.clock.Now().Format(time.RFC3339)
+	for i, thought := range t.thoughts {
+		if !strings.Contains(normalize(thought.Thought), query) {
+			continue
+		}
+		matched++
+		if thought.Resolved {
+			continue
+		}
+		t.thoughts[i].Resolved = true
+		t.thoughts[i].ResolvedAt = now
+		t.thoughts[i].UpdatedAt = now
+		resolved = append(resolved, i+1)
+	}
+
+	if matched == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "No matching thoughts found."}}}, nil
+	}
+	text := fmt.Sprintf("Resolved %d thought(s).", len(resolved))
+	if len(resolved) > 0 {
+		indices := []string{}
+		for _, i := range resolved {
+			indices = append(indices, fmt.Sprintf("#%d", i))
+		}
+		text = fmt.Sprintf("Resolved %d thought(s): %s.", len(resolved), strings.Join(indices, ", "))
+	}
+	if skipped := matched - len(resolved); skipped > 0 {
+		text += fmt.Sprintf(" %d matching thought(s) were already resolved.", skipped)
+	}
+	if len(resolved) > 0 {
+		text += t.persist(sess)
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: text}},
+		StructuredContent: map[string]any{"resolved": resolved, "skipped": matched - len(resolved)},
+	}, nil
+}
+
+type LockThoughtInput struct {
+	Index int `json:"index" jsonschema:"the 1-based index of the thought"`
+}
+
+// LockThought is a tool that locks a thought. The text and dependencies of a
+// locked thought cannot be changed, pruning summarized thoughts keeps it, and
+// clearing the log requires confirmation. Annotating and resolving a locked
+// thought is still possible.
+func (t *ThinkTool) LockThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[LockThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	return t.setLocked(sess, params.Arguments.Index, true)
+}
+
+// UnlockThought is a tool that unlocks a locked thought.
+func (t *ThinkTool) UnlockThought(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[LockThoughtInput]) (*mcp.CallToolResultFor[any], error) {
+	return t.setLocked(sess, params.Arguments.Index, false)
+}
+
+func (t *ThinkTool) setLocked(sess *mcp.ServerSession, index int, locked bool) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i, err := t.thoughtAt(index)
+	if err != nil {
+		return nil, err
+	}
+	state := "unlocked"
+	if locked {
+		state = "locked"
+	}
+	if t.thoughts[i].Locked == locked {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Thought #%d is already %s.", i+1, state)}}}, nil
+	}
+
+	t.thoughts[i].Locked = locked
+	t.thoughts[i].UpdatedAt = t.clock.Now().Format(time.RFC3339)
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Thought #%d is now %s.%s", i+1, state, warning)}}}, nil
+}
+
+// errLocked returns the error for edits of the locked thought at position i.
+func errLocked(i int) error {
+	return toolError(CodeLocked, "thought #%d is locked. Unlock it with unlock_thought first.", i+1)
+}
+
+// GetOpenThoughts is a tool that returns the thoughts that are not resolved.
+func (t *ThinkTool) GetOpenThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.thoughts) == 0 {
+		return nil, t.errNoThoughts()
+	}
+
+	now := t.clock.Now()
+	thoughts := []string{}
+	for i, thought := range t.thoughts {
+		if !thought.Resolved && !thought.expired(now) {
+			thoughts = append(thoughts, t.formatThought(i, thought))
+		}
+	}
+	if len(thoughts) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "All thoughts are resolved."}}}, nil
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
+}
+
+type GetConfidentThoughtsInput struct {
+	Threshold float64 `json:"threshold" jsonschema:"the minimum confidence from 0 to 1"`
+}
+
+// GetConfidentThoughts is a tool that returns the thoughts recorded with a
+// confidence at or above a threshold. Thoughts without a confidence are
+// never returned.
+func (t *ThinkTool) GetConfidentThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[GetConfidentThoughtsInput]) (*mcp.CallToolResultFor[any], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	threshold := params.Arguments.Threshold
+	if threshold < 0 || threshold > 1 {
+		return nil, toolError(CodeOutOfRange, "threshold must be between 0 and 1, got %g", threshold)
+	}
 	if len(t.thoughts) == 0 {
-		return nil, errors.New("no thoughts recorded. Use the think tool to record a thought first.")
+		return nil, t.errNoThoughts()
 	}
 
+	now := t.clock.Now()
 	thoughts := []string{}
 	for i, thought := range t.thoughts {
-		thoughts = append(thoughts, fmt.Sprintf("Thought #%d at %s:\n%s\n", i+1, thought.CreatedAt, thought.Thought))
+		if thought.Confidence != nil && *thought.Confidence >= threshold && !thought.expired(now) {
+			thoughts = append(thoughts, t.formatThought(i, thought))
+		}
+	}
+	if len(thoughts) == 0 {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No thoughts with a confidence of at least %g.", threshold)}}}, nil
 	}
 	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(thoughts, "\n")}}}, nil
 }
 
-func (t *ThinkTool) ClearThoughts(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+// RestoreCleared is a tool that restores the thoughts removed by the last
+// clear, as long as no thought has been recorded since.
+func (t *ThinkTool) RestoreCleared(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	t.thoughts = []ThoughtItem{}
-	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "Thoughts cleared."}}}, nil
+	if len(t.lastCleared) == 0 {
+		return nil, toolError(CodeNotFound, "nothing to restore. Thoughts can only be restored right after a clear, before any new thought is recorded.")
+	}
+
+	n, offset := len(t.lastCleared), len(t.thoughts)
+	for _, item := range t.lastCleared {
+		item.relink(func(index int) int { return index + offset })
+		t.thoughts = append(t.thoughts, item)
+	}
+	t.lastCleared = nil
+	warning := t.persist(sess)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Restored %d thought(s).%s", n, warning)}}}, nil
+}
+
+// PauseRecording is a tool that makes think discard new thoughts, e.g. while
+// the agent executes actions, until ResumeRecording is called. The recorded
+// thoughts can still be read and searched. The log is shared by all sessions,
+// so the pause applies to all of them.
+func (t *ThinkTool) PauseRecording(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	return t.setRecording(false)
+}
+
+// ResumeRecording is a tool that makes think record thoughts again after
+// PauseRecording.
+func (t *ThinkTool) ResumeRecording(ctx context.Context, sess *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+	return t.setRecording(true)
+}
+
+func (t *ThinkTool) setRecording(enabled bool) (*mcp.CallToolResultFor[any], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	text := "Recording is paused: new thoughts are not stored until resume_recording is called."
+	if enabled {
+		text = "Recording is resumed: new thoughts are stored again."
+	}
+	if t.recordingPaused == !enabled {
+		text = "Nothing changed. " + text
+	}
+	t.recordingPaused = !enabled
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+}
+
+// addTool adds a tool to the server like mcp.AddTool, with error codes for
+// the errors returned by h.
+func addTool[In any](server *mcp.Server, tool *mcp.Tool, h mcp.ToolHandlerFor[In, any]) {
+	mcp.AddTool(server, tool, withErrorCodes(h))
+}
+
+// frameResponses is a server middleware that places -response-prefix before
+// the first and -response-suffix after the last text content of every tool
+// result.
+func (t *ThinkTool) frameResponses(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, sess *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		res, err := next(ctx, sess, method, params)
+		r, ok := res.(*mcp.CallToolResult)
+		if method != "tools/call" || !ok || err != nil {
+			return res, err
+		}
+		first, last := -1, -1
+		for i, c := range r.Content {
+			if _, ok := c.(*mcp.TextContent); ok {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			return res, err
+		}
+		if prefix := t.cfg.ResponsePrefix; prefix != "" {
+			text := *r.Content[first].(*mcp.TextContent)
+			text.Text = prefix + text.Text
+			r.Content[first] = &text
+		}
+		if suffix := t.cfg.ResponseSuffix; suffix != "" {
+			text := *r.Content[last].(*mcp.TextContent)
+			text.Text += suffix
+			r.Content[last] = &text
+		}
+		return r, nil
+	}
 }
 
 func main() {
+	var cfg Config
+	cfg.registerFlags(flag.CommandLine)
+	flag.Parse()
+
+	logger := slog.Default()
+	if err := applyEnv(flag.CommandLine); err != nil {
+		logger.Error("invalid configuration", slog.Any("error", err))
+		os.Exit(2)
+	}
+	if err := cfg.validate(); err != nil {
+		logger.Error("invalid configuration", slog.Any("error", err))
+		os.Exit(2)
+	}
+
 	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "think-tool",
+		Name:    serverName,
 		Version: "v0.0.1",
 	}, nil)
 
-	thinkTool := &ThinkTool{}
+	thinkTool := NewThinkTool(cfg, nil)
+	thinkTool.server = server
+	if err := thinkTool.load(); err != nil {
+		logger.Error("failed to load thoughts", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if err := thinkTool.loadThoughtSchema(); err != nil {
+		logger.Error("failed to load thought schema", slog.Any("error", err))
+		os.Exit(1)
+	}
+	server.AddReceivingMiddleware(thinkTool.recordLatency)
+	server.AddReceivingMiddleware(thinkTool.pruneBeforeCalls)
+	if cfg.ResponsePrefix != "" || cfg.ResponseSuffix != "" {
+		server.AddReceivingMiddleware(thinkTool.frameResponses)
+	}
+	thinkTool.watchIdle(cfg.IdleWarn)
+	go thinkTool.sweepExpired(time.Minute)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name: "think",
 		Description: `Use this tool to think about something.
 It will not obtain new information or change anything, but just append the thought to the log.
 Use it when complex reasoning or cache memory is needed.`,
 	}, thinkTool.Think)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
+		Name:        "record_decision",
+		Description: `Record a decision with its context and consequences as a thought tagged decision. Use it for choices that later reasoning should respect, e.g. picking a library or ruling out an approach.`,
+	}, thinkTool.RecordDecision)
+
+	addTool(server, &mcp.Tool{
+		Name:        "think_with_reminder",
+		Description: `Record a thought like think that becomes due after remind_after, e.g. 2h, to follow up on it later. Due reminders are returned by get_due_reminders until the thought is resolved.`,
+	}, thinkTool.ThinkWithReminder)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_due_reminders",
+		Description: `Retrieve the thoughts recorded with think_with_reminder whose reminder is due and that are not resolved yet.`,
+	}, thinkTool.GetDueReminders)
+
+	addTool(server, &mcp.Tool{
+		Name:        "think_unless_exists",
+		Description: `Record a thought like think, but only if no recorded thought contains guard_query, e.g. to avoid noting the same finding twice. Otherwise the first matching thought is returned and nothing is recorded.`,
+	}, thinkTool.ThinkUnlessExists)
+
+	addTool(server, &mcp.Tool{
 		Name:        "get_thoughts",
-		Description: `Retrieve all thoughts recorded in the current session. This tool helps review the thinking process that has occurred so far.`,
+		Description: `Retrieve all thoughts recorded in the current session. This tool helps review the thinking process that has occurred so far. Set min_length or max_length to only return thoughts within a length range, or after_index to only return thoughts newer than a previously seen latest_index; thoughts keep their original numbering. Set include_lang to label each thought with its detected language.`,
 	}, thinkTool.GetThoughts)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
+		Name:        "set_goal",
+		Description: `Set the goal of the session, i.e. what the reasoning is for. The goal is shown above the thoughts returned by get_thoughts. Pass an empty goal to remove it.`,
+	}, thinkTool.SetGoal)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_goal",
+		Description: `Retrieve the goal of the session set with set_goal.`,
+	}, thinkTool.GetGoal)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_annotated_thoughts",
+		Description: `Retrieve all thoughts with the notes of external reviewers merged in below each thought. Reviewers write their notes to the JSON file given with -review-file, keyed by thought ID.`,
+	}, thinkTool.GetAnnotatedThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_redacted_thoughts",
+		Description: `Retrieve all thoughts with secrets such as API keys, tokens, passwords, private keys and email addresses replaced by [REDACTED]. Use it before sharing or exporting the reasoning. The stored thoughts are not changed.`,
+	}, thinkTool.GetRedactedThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_with_toc",
+		Description: `Retrieve all thoughts recorded so far, preceded by a table of contents listing each thought's index and a short title taken from its first line. Use it to find the relevant parts of a long log.`,
+	}, thinkTool.GetThoughtsWithTOC)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_by_kind",
+		Description: `Retrieve the thoughts recorded with a kind, one of thought, action or observation, e.g. all observations of a ReAct-style loop.`,
+	}, thinkTool.GetThoughtsByKind)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_templated",
+		Description: `Retrieve all thoughts, each rendered with the template the server was started with (-render-template).`,
+	}, thinkTool.GetThoughtsTemplated)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_wrapped_thoughts",
+		Description: `Retrieve all thoughts with their text word-wrapped to the given column width, for display in terminals and other fixed-width UIs. A width of 0 returns the text unwrapped.`,
+	}, thinkTool.GetWrappedThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_with_links",
+		Description: `Retrieve all thoughts with a permalink to each, e.g. to share single thoughts in web workflows. Permalinks are only available when the server runs in HTTP mode.`,
+	}, thinkTool.GetThoughtsWithLinks)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_narrative",
+		Description: `Retrieve the thoughts as a single paragraph of prose in chronological order, each introduced by a transition phrase such as "First," "Then," and "Finally,". The phrases can be replaced. Useful for writing up the reasoning.`,
+	}, thinkTool.GetNarrative)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thought_headlines",
+		Description: `Retrieve a compact index of the log: the index and first line of every thought, shortened to max_words words. Use it to scan a long log, then fetch the full text of a thought with get_thought.`,
+	}, thinkTool.GetThoughtHeadlines)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_recently_modified",
+		Description: `Retrieve the thoughts that were most recently recorded, edited, annotated or otherwise changed, most recent first. Use it to see what was worked on lately.`,
+	}, thinkTool.GetRecentlyModified)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_page",
+		Description: `Retrieve the thoughts one page at a time. Pass the next_cursor of the previous page as after_id to get the next one; pages stay correct when thoughts are added or removed in between.`,
+	}, thinkTool.GetThoughtsPage)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thought",
+		Description: `Retrieve a single thought by its 1-based index.`,
+	}, thinkTool.GetThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "expand_thought",
+		Description: `Return the full text of a thought whose preview was truncated with "..." by another tool. Pass either its index or the preview itself; if several thoughts begin with the preview, all of them are returned with their indices.`,
+	}, thinkTool.ExpandThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "random_thought",
+		Description: `Return one recorded thought chosen at random, e.g. to revisit an earlier idea. Pass a seed to make the choice reproducible.`,
+	}, thinkTool.RandomThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_by_day",
+		Description: `Retrieve all thoughts recorded in the current session grouped by the calendar day they were recorded on, in the time zone of the server (UTC by default). Days without thoughts are omitted.`,
+	}, thinkTool.GetThoughtsByDay)
+
+	addTool(server, &mcp.Tool{
+		Name:        "has_thought",
+		Description: `Check whether a thought with the given text has already been recorded, e.g. to avoid recording the same thought twice. Use mode "normalized" to ignore case and whitespace differences.`,
+	}, thinkTool.HasThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "fuzzy_search_thoughts",
+		Description: `Search the recorded thoughts for a query, tolerating typos and paraphrases. Returns the most relevant thoughts ranked by a relevance score between 0 and 1.`,
+	}, limited(thinkTool, thinkTool.FuzzySearchThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_relevant_thoughts",
+		Description: `Get the recorded thoughts most relevant to a context, e.g. the current question, ranked by TF-IDF cosine similarity over the log. Words that are rare in the log weigh more than common ones. Use it to recall prior reasoning before continuing.`,
+	}, limited(thinkTool, thinkTool.GetRelevantThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "think_and_relate",
+		Description: `Record a thought like think, with the same options, and get the earlier thoughts most related to it, ranked like get_relevant_thoughts. Use it to connect a new idea to prior reasoning without a separate search.`,
+	}, limited(thinkTool, thinkTool.ThinkAndRelate))
+
+	addTool(server, &mcp.Tool{
+		Name:        "glob_search_thoughts",
+		Description: `Return the thoughts whose whole text matches a glob pattern, where * matches any text, ? any single character and [...] a character class. Use *word* to find thoughts containing a word.`,
+	}, limited(thinkTool, thinkTool.GlobSearchThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_by_tag_pattern",
+		Description: `Return the thoughts with at least one tag matching a regular expression, e.g. ^area/ for all tags below area/.`,
+	}, limited(thinkTool, thinkTool.GetThoughtsByTagPattern))
+
+	addTool(server, &mcp.Tool{
+		Name:        "query_thoughts",
+		Description: `Filter, sort and page the thoughts in one call. All given filters must match: tags (all of them), query (a case-insensitive substring) and the time range since to until. Matches are sorted by sort_by (created, updated, priority or length) and returned limit at a time from offset, along with the total number of matches.`,
+	}, limited(thinkTool, thinkTool.QueryThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_referencing",
+		Description: `Retrieve the thoughts that cite a reference, e.g. a file, URL or issue number given in the references of think. Matches any reference containing the given text.`,
+	}, thinkTool.GetThoughtsReferencing)
+
+	addTool(server, &mcp.Tool{
+		Name:        "log_complexity",
+		Description: `Estimate how much content the log holds: number of thoughts, characters, words, reading time at 200 words per minute and model tokens. Cheaper than retrieving the log to judge whether it should be summarized.`,
+	}, thinkTool.LogComplexity)
+
+	addTool(server, &mcp.Tool{
+		Name:        "latency_report",
+		Description: `Report how long the tool calls served so far took: call count, p50, p95 and maximum latency per tool.`,
+	}, thinkTool.LatencyReport)
+
+	addTool(server, &mcp.Tool{
+		Name:        "throughput_report",
+		Description: `Report how fast thoughts are recorded: thoughts per minute over the whole session and within the last minutes (default 5), and the most thoughts recorded within a single minute.`,
+	}, thinkTool.ThroughputReport)
+
+	addTool(server, &mcp.Tool{
+		Name:        "activity_histogram",
+		Description: `Count the thoughts recorded per time bucket of the given width, e.g. 1h, and show the counts as a sparkline. Buckets without thoughts are counted as zero.`,
+	}, thinkTool.ActivityHistogram)
+
+	addTool(server, &mcp.Tool{
+		Name:        "runtime_stats",
+		Description: `Report the resource use of the server (goroutines, heap, uptime) along with the number of thoughts, sessions, snapshots and shares, e.g. to spot leaks in a long-running server. Read-only.`,
+	}, thinkTool.RuntimeStats)
+
+	addTool(server, &mcp.Tool{
+		Name:        "summarize_via_sampling",
+		Description: `Ask the client's model to summarize all recorded thoughts and record the summary as a new thought tagged summary. Set prune to remove the summarized thoughts afterwards. The summary must pass the same checks as think, e.g. set category if the server requires one. Requires a client that supports MCP sampling.`,
+	}, limited(thinkTool, thinkTool.SummarizeViaSampling))
+
+	addTool(server, &mcp.Tool{
+		Name:        "find_contradictions",
+		Description: `Find pairs of thoughts that may contradict each other, e.g. "the cache is enabled" and "the cache is not enabled". This is a word-based heuristic: it flags similar thoughts where only one is negated or that use opposite words such as true/false. Check each candidate pair yourself.`,
+	}, limited(thinkTool, thinkTool.FindContradictions))
+
+	addTool(server, &mcp.Tool{
+		Name:        "vocabulary_stats",
+		Description: `Report how many distinct words the recorded thoughts use and which words are the most frequent, ignoring stopwords. Words are lowercased runs of letters and digits.`,
+	}, limited(thinkTool, thinkTool.VocabularyStats))
+
+	addTool(server, &mcp.Tool{
+		Name:        "tag_stats",
+		Description: `Report per tag how many thoughts have it, their total and average length and the period over which they were recorded, sorted by thought count. Thoughts without tags are grouped as (untagged).`,
+	}, thinkTool.TagStats)
+
+	addTool(server, &mcp.Tool{
+		Name:        "rank_thoughts",
+		Description: `Rank the thoughts by a heuristic importance score combining priority, length, locked and resolved status, how many thoughts follow from or depend on it, and recency. Returns the top thoughts with the breakdown of their score. The weights are configured by the server.`,
+	}, limited(thinkTool, thinkTool.RankThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "cluster_thoughts",
+		Description: `Group the recorded thoughts by rough topic using bag-of-words similarity. Returns each cluster with its member thoughts and a representative thought, useful for summarizing a sprawling session.`,
+	}, limited(thinkTool, thinkTool.ClusterThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "replace_in_thoughts",
+		Description: `Find and replace text across all recorded thoughts, e.g. to fix a consistently misspelled term. Set regex to use a regular expression pattern. Reports how many thoughts changed and how many replacements were made.`,
+	}, limited(thinkTool, thinkTool.ReplaceInThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_json",
+		Description: `Export all recorded thoughts as a JSON document. Set max_bytes to receive the export in pages of at most that size; when more thoughts remain, next_cursor holds the token to pass as cursor for the next page. Pages stay correct when thoughts are added or removed in between. Set checksum to embed a checksum that import_thoughts verifies. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportJSON))
+
+	addTool(server, &mcp.Tool{
+		Name:        "sync_since",
+		Description: `Check whether the log changed since a fingerprint returned by an earlier call. Returns only "no changes" if it did not, otherwise the whole log as an export_json document with the new fingerprint. Pass an empty fingerprint on the first call.`,
+	}, limited(thinkTool, thinkTool.SyncSince))
+
+	addTool(server, &mcp.Tool{
+		Name:        "import_thoughts",
+		Description: `Append the thoughts of a JSON document produced by export_json to the log. If the document carries a checksum, the import is rejected when it does not match. It is also rejected if any thought violates the schema, category or tag restrictions of the server.`,
+	}, thinkTool.ImportThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "pull_from_peer",
+		Description: `Append the thoughts of another think-tool instance running with -transport http, given its base URL. Thoughts already present (by ID) are skipped. The pull is rejected if any new thought violates the schema, category or tag restrictions of the server.`,
+	}, limited(thinkTool, thinkTool.PullFromPeer))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_markdown",
+		Description: `Export all recorded thoughts as a Markdown document. Set max_bytes to receive the export in pages of at most that size; a truncated page ends with the cursor to pass for the next page. Pages stay correct when thoughts are added or removed in between. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportMarkdown))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_issue_body",
+		Description: `Export all recorded thoughts as the body of a GitHub issue: a summary, a task list of resolved and open thoughts if any is resolved, and a collapsible section per thought. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportIssueBody))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_csv",
+		Description: `Export all recorded thoughts as CSV with the columns index, created_at, tags and thought, e.g. for importing into a spreadsheet. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportCSV))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_yaml",
+		Description: `Export all recorded thoughts as a YAML list with the same fields as export_json, writing multi-line thoughts as block scalars. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportYAML))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_thought_chain",
+		Description: `Export a single thought together with the chain of thoughts it follows from (via parent_index) as a standalone Markdown document, ordered from the root to the selected thought.`,
+	}, thinkTool.ExportThoughtChain)
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_mermaid",
+		Description: `Export the recorded thoughts as a Mermaid flowchart (graph TD). Each thought is a node labeled with its index and a shortened text, with solid edges from parent_index links and dotted edges from depends_on links; unlinked thoughts are isolated nodes. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportMermaid))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_otel_events",
+		Description: `Export the recorded thoughts as a JSON array of OpenTelemetry span events (OTLP JSON encoding), one event named thought per thought, timed at its creation and with its index, id, text, tags, priority, parent and resolution as attributes. Use it to ship the reasoning to a tracing backend. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportOTelEvents))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_flashcards",
+		Description: `Export the recorded thoughts as flashcards in Anki's tab-separated text format, one card per thought. A thought is split into question and answer at the first delimiter, by default a question mark; thoughts without it get their first line on the front and their full text on the back. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportFlashcards))
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_feed",
+		Description: `Export the recorded thoughts as an Atom feed with one entry per thought, e.g. to follow a long-running agent's reasoning in a feed reader. Set and_clear to clear the log in the same step, and confirm if it contains locked thoughts.`,
+	}, limited(thinkTool, thinkTool.ExportFeed))
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_context_block",
+		Description: `Retrieve all recorded thoughts as a single numbered block with a framing header, ready to be re-injected into a prompt. Set max_bytes to keep only the most recent thoughts that fit.`,
+	}, thinkTool.GetContextBlock)
+
+	addTool(server, &mcp.Tool{
+		Name:        "sort_thoughts",
+		Description: `Permanently reorder the recorded thoughts by time, length, priority or alpha(betical) order and renumber them. Unlike a sorted view, this changes the order for all subsequent calls. Thoughts with equal keys keep their relative order.`,
+	}, thinkTool.SortThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "annotate_thought",
+		Description: `Attach a follow-up note to a recorded thought, e.g. "superseded by #7", without changing the original text. Notes are shown beneath the thought.`,
+	}, thinkTool.AnnotateThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "resolve_thought",
+		Description: `Mark a recorded thought as resolved, e.g. once the task it describes has been acted on. Resolved thoughts are marked in get_thoughts and left out of get_open_thoughts.`,
+	}, thinkTool.ResolveThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "resolve_matching",
+		Description: `Resolve every unresolved thought that contains the query text, ignoring case and whitespace differences, e.g. to close all tasks of a finished subproblem at once. Already resolved thoughts are skipped.`,
+	}, limited(thinkTool, thinkTool.ResolveMatching))
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_open_thoughts",
+		Description: `Retrieve the recorded thoughts that have not been resolved yet.`,
+	}, thinkTool.GetOpenThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_confident_thoughts",
+		Description: `Retrieve the thoughts recorded with a confidence at or above a threshold between 0 and 1, to separate firm conclusions from speculation.`,
+	}, thinkTool.GetConfidentThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_thoughts_by_sentiment",
+		Description: `Return the thoughts whose sentiment score, from -1 (stuck, confused) to 1 (confident, making progress), is above the threshold, or below it with below set. The score is an experimental heuristic counting words of a small built-in list, e.g. to find where the reasoning got stuck.`,
+	}, thinkTool.GetThoughtsBySentiment)
+
+	addTool(server, &mcp.Tool{
+		Name:        "lock_thought",
+		Description: `Lock a thought, e.g. a final conclusion, so that its text and dependencies cannot be changed and it is kept when summarized thoughts are pruned. Notes can still be added and the thought can still be resolved.`,
+	}, thinkTool.LockThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "unlock_thought",
+		Description: `Unlock a previously locked thought so that it can be edited again.`,
+	}, thinkTool.UnlockThought)
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_dependencies",
+		Description: `Set the thoughts that must be resolved before a thought can be acted on, replacing its previous dependencies. Dependencies that would form a cycle are rejected.`,
+	}, thinkTool.SetDependencies)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_ready_thoughts",
+		Description: `Retrieve the unresolved thoughts whose dependencies are all resolved, i.e. the ones that can be acted on next.`,
+	}, thinkTool.GetReadyThoughts)
+
+	addTool(server, &mcp.Tool{
+		Name:        "find_dangling_references",
+		Description: `Report the parent and dependency links that point to thoughts that do not exist, and with mentions also the #N mentions in the text, each with the index of the thought containing it. Use it to check the reasoning graph after edits or imports.`,
+	}, thinkTool.FindDanglingReferences)
+
+	addTool(server, &mcp.Tool{
+		Name:        "tag_matching",
+		Description: `Add a tag to every thought that contains the query text, ignoring case and whitespace differences. Thoughts that already have the tag are left unchanged.`,
+	}, limited(thinkTool, thinkTool.TagMatching))
+
+	addTool(server, &mcp.Tool{
 		Name:        "clear_thoughts",
-		Description: `Clear all recorded thoughts from the current session. Use this to start fresh if the thinking process needs to be reset.`,
+		Description: `Clear all recorded thoughts from the current session. Use this to start fresh if the thinking process needs to be reset. The goal is removed as well unless keep_goal is set. If any thought is locked, confirm must be set.`,
 	}, thinkTool.ClearThoughts)
 
-	logger := slog.Default()
-	logger.Info("starting mcp stdio server ...")
-	if err := server.Run(context.Background(), mcp.NewStdioTransport()); err != nil {
+	addTool(server, &mcp.Tool{
+		Name:        "trim_to_last",
+		Description: `Remove all but the n most recent thoughts and renumber the rest. Locked thoughts are always kept. Use it to drop old context while keeping the latest reasoning.`,
+	}, thinkTool.TrimToLast)
+
+	addTool(server, &mcp.Tool{
+		Name:        "canonicalize_thoughts",
+		Description: `Clean up the log in one step: normalize the whitespace of every thought, remove thoughts that duplicate an earlier one and renumber the rest, reporting what changed. Locked thoughts are left alone. Running it twice changes nothing the second time.`,
+	}, limited(thinkTool, thinkTool.CanonicalizeThoughts))
+
+	addTool(server, &mcp.Tool{
+		Name:        "restore_cleared",
+		Description: `Restore the thoughts removed by the last clear_thoughts call. This only works until the next clear or until a new thought is recorded.`,
+	}, thinkTool.RestoreCleared)
+
+	addTool(server, &mcp.Tool{
+		Name:        "pause_recording",
+		Description: `Pause recording: until resume_recording is called, think acknowledges thoughts without storing them. Recorded thoughts can still be read and searched. Use it to keep noisy loops, e.g. while executing actions, out of the log.`,
+	}, thinkTool.PauseRecording)
+
+	addTool(server, &mcp.Tool{
+		Name:        "resume_recording",
+		Description: `Resume recording after pause_recording, so that think stores thoughts again.`,
+	}, thinkTool.ResumeRecording)
+
+	addTool(server, &mcp.Tool{
+		Name:        "create_snapshot",
+		Description: `Save a copy of the current thoughts under a name, e.g. before trying a risky line of reasoning. An existing snapshot with the same name is replaced.`,
+	}, thinkTool.CreateSnapshot)
+
+	addTool(server, &mcp.Tool{
+		Name:        "restore_snapshot",
+		Description: `Replace the current thoughts with the contents of a named snapshot. Set confirm if the log contains locked thoughts.`,
+	}, thinkTool.RestoreSnapshot)
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_snapshots",
+		Description: `List the available snapshots with their number of thoughts and creation time.`,
+	}, thinkTool.ListSnapshots)
+
+	addTool(server, &mcp.Tool{
+		Name:        "diff_snapshots",
+		Description: `Compare two snapshots and report which thoughts were added, removed or modified from the older to the newer one, matched by thought ID.`,
+	}, thinkTool.DiffSnapshots)
+
+	addTool(server, &mcp.Tool{
+		Name:        "compare_to_baseline",
+		Description: `Compare the current thoughts to a baseline export_json document, e.g. from an earlier run of the same task, and report how many thoughts are unchanged, new, missing or changed with examples of each. Thoughts are matched by text, ignoring case and whitespace.`,
+	}, limited(thinkTool, thinkTool.CompareToBaseline))
+
+	addTool(server, &mcp.Tool{
+		Name:        "create_share_link",
+		Description: `Freeze a read-only copy of the current thoughts under a short ID that can be handed to others and resolved with get_shared. Later changes to the log do not affect the share.`,
+	}, thinkTool.CreateShareLink)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_shared",
+		Description: `Return the thoughts of a share created by create_share_link.`,
+	}, thinkTool.GetShared)
+
+	addTool(server, &mcp.Tool{
+		Name:        "verify_store",
+		Description: `Check that the persistence file can be loaded and is consistent: every thought has text and a valid timestamp and no two thoughts share an ID. Reports the first problem found or ok. Nothing is modified.`,
+	}, thinkTool.VerifyStore)
+
+	addTool(server, &mcp.Tool{
+		Name:        "rebuild_from_journal",
+		Description: `Replace the current thoughts with the log reconstructed from the journal written with -journal, e.g. after the persistence file was lost or damaged. Corrupt lines are skipped and reported. Set confirm if the log contains locked thoughts.`,
+	}, thinkTool.RebuildFromJournal)
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_audit_log",
+		Description: `Export the audit log as JSON: every addition, change, deletion, reordering and clear of thoughts with its time and, in HTTP mode, the session that made it. Only the latest events are kept; the number of dropped older events is included.`,
+	}, thinkTool.ExportAuditLog)
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_persistence",
+		Description: `Disable or re-enable persistence for the current session, e.g. while recording scratch thoughts that should not be kept. While disabled, the thoughts the session records are kept in memory only, never written to the persistence file or the journal, and lost on clear or restart. Other sessions are not affected. Reports the resulting state.`,
+	}, thinkTool.SetPersistence)
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_config",
+		Description: `Get the effective configuration of the server: every option with its value after applying flags and THINK_* environment variables, its default and whether it was changed. Sensitive values are redacted. Useful to debug a deployment.`,
+	}, thinkTool.GetConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go thinkTool.flushEvery(ctx, cfg.FlushInterval)
+
+	var err error
+	switch cfg.Transport {
+	case "http":
+		logger.Info("starting mcp http server ...", slog.String("addr", cfg.Addr))
+		err = thinkTool.serveHTTP(ctx, server)
+	default:
+		logger.Info("starting mcp stdio server ...")
+		err = server.Run(ctx, mcp.NewStdioTransport())
+	}
+	if err != nil && ctx.Err() == nil {
 		logger.Error("failed to run server", slog.Any("error", err))
 	}
+	thinkTool.stopIdle()
+	thinkTool.flush()
 }
 
 func tidyThought(thought string) string {
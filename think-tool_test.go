@@ -0,0 +1,147 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// invoke calls the tool handler h directly with the arguments in.
+func invoke[In any](t *testing.T, h mcp.ToolHandlerFor[In, any], in In) *mcp.CallToolResultFor[any] {
+	t.Helper()
+	res, err := h(context.Background(), nil, &mcp.CallToolParamsFor[In]{Arguments: in})
+	if err != nil {
+		t.Fatalf("tool call failed: %v", err)
+	}
+	return res
+}
+
+func TestThinkUsesClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tool := NewThinkTool(Config{}, clock)
+
+	invoke(t, tool.Think, ThinkInput{Thought: "first", TTL: "1h"})
+	item := tool.thoughts[0]
+	if item.CreatedAt != "2025-03-01T12:00:00Z" {
+		t.Errorf("CreatedAt = %q, want the time of the clock", item.CreatedAt)
+	}
+	if item.ExpiresAt != "2025-03-01T13:00:00Z" {
+		t.Errorf("ExpiresAt = %q, want an hour after the time of the clock", item.ExpiresAt)
+	}
+}
+
+func TestDueReminders(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tool := NewThinkTool(Config{}, clock)
+
+	invoke(t, tool.ThinkWithReminder, ThinkWithReminderInput{Thought: "check the benchmark", RemindAfter: "2h"})
+	invoke(t, tool.Think, ThinkInput{Thought: "plain thought"})
+
+	clock.now = clock.now.Add(2*time.Hour - time.Second)
+	if text := resultText(t, invoke(t, tool.GetDueReminders, struct{}{})); text != "No reminders are due." {
+		t.Errorf("get_due_reminders before the reminder is due = %q", text)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	text := resultText(t, invoke(t, tool.GetDueReminders, struct{}{}))
+	if !strings.Contains(text, "check the benchmark") || strings.Contains(text, "plain thought") {
+		t.Errorf("get_due_reminders once the reminder is due = %q, want only the reminder", text)
+	}
+
+	invoke(t, tool.ResolveThought, ResolveThoughtInput{Index: 1})
+	if text := resultText(t, invoke(t, tool.GetDueReminders, struct{}{})); text != "No reminders are due." {
+		t.Errorf("get_due_reminders after resolving the reminder = %q", text)
+	}
+}
+
+func TestRandomThoughtSeed(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tool := NewThinkTool(Config{}, clock)
+	for _, thought := range []string{"a", "b", "c", "d", "e"} {
+		invoke(t, tool.Think, ThinkInput{Thought: thought})
+	}
+	pick := func(seed int64) int {
+		res := invoke(t, tool.RandomThought, RandomThoughtInput{Seed: seed})
+		return res.StructuredContent.(RandomThoughtResult).Index
+	}
+
+	picked := map[int]bool{}
+	for seed := int64(1); seed <= 20; seed++ {
+		index := pick(seed)
+		if again := pick(seed); again != index {
+			t.Fatalf("seed %d picked thought #%d and then #%d, want the same thought", seed, index, again)
+		}
+		picked[index] = true
+	}
+	if len(picked) < 2 {
+		t.Errorf("20 seeds all picked the same thought")
+	}
+
+	// Expired thoughts are never picked.
+	invoke(t, tool.Think, ThinkInput{Thought: "short-lived", TTL: "1m"})
+	clock.now = clock.now.Add(time.Minute)
+	for seed := int64(1); seed <= 20; seed++ {
+		if index := pick(seed); index == 6 {
+			t.Errorf("seed %d picked the expired thought", seed)
+		}
+	}
+}
+
+// TestConcurrentReadersAndWriters exercises the read and write locking of the
+// tools. It is meant to be run with the race detector: go test -race.
+func TestConcurrentReadersAndWriters(t *testing.T) {
+	tool := NewThinkTool(Config{}, nil)
+	invoke(t, tool.Think, ThinkInput{Thought: "seed thought about locking"})
+
+	const workers, calls = 4, 50
+	var wg sync.WaitGroup
+	run := func(name string, f func(i int) error) {
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range calls {
+					if err := f(i); err != nil {
+						t.Errorf("%s failed: %v", name, err)
+						return
+					}
+				}
+			}()
+		}
+	}
+	ctx := context.Background()
+	run("think", func(i int) error {
+		_, err := tool.Think(ctx, nil, &mcp.CallToolParamsFor[ThinkInput]{Arguments: ThinkInput{Thought: fmt.Sprintf("thought %d about locking", i), Tags: []string{"race"}}})
+		return err
+	})
+	run("get_thoughts", func(int) error {
+		_, err := tool.GetThoughts(ctx, nil, &mcp.CallToolParamsFor[GetThoughtsInput]{})
+		return err
+	})
+	run("export_json", func(int) error {
+		_, err := tool.ExportJSON(ctx, nil, &mcp.CallToolParamsFor[ExportJSONInput]{})
+		return err
+	})
+	run("fuzzy_search_thoughts", func(int) error {
+		_, err := tool.FuzzySearchThoughts(ctx, nil, &mcp.CallToolParamsFor[FuzzySearchThoughtsInput]{Arguments: FuzzySearchThoughtsInput{Query: "lockng"}})
+		return err
+	})
+	run("get_relevant_thoughts", func(int) error {
+		_, err := tool.GetRelevantThoughts(ctx, nil, &mcp.CallToolParamsFor[GetRelevantThoughtsInput]{Arguments: GetRelevantThoughtsInput{Context: "locking"}})
+		return err
+	})
+	wg.Wait()
+
+	if want := 1 + workers*calls; len(tool.thoughts) != want {
+		t.Errorf("thoughts after concurrent calls = %d, want %d", len(tool.thoughts), want)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// watchIdle starts a background timer that logs a warning whenever no
+// thought has been recorded for the given duration. It is a no-op if d is
+// not positive. The warning repeats every d until a thought is recorded.
+func (t *ThinkTool) watchIdle(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.idleWarn = d
+	t.idleTimer = t.clock.AfterFunc(d, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		slog.Warn("no thoughts recorded recently, the agent may be stuck", slog.Duration("idle", d), slog.Int("thoughts", len(t.thoughts)))
+		t.idleTimer.Reset(d)
+	})
+}
+
+// resetIdle restarts the idle timer after a thought was recorded. It must be
+// called with t.mu held.
+func (t *ThinkTool) resetIdle() {
+	if t.idleTimer != nil {
+		t.idleTimer.Reset(t.idleWarn)
+	}
+}
+
+// stopIdle stops the idle timer started by watchIdle, if any, e.g. on
+// shutdown.
+func (t *ThinkTool) stopIdle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+	}
+}
+
+// sweepExpired periodically removes thoughts whose TTL has elapsed, so that
+// they do not linger in memory and on disk while no tool is called. It never
+// returns and is meant to be run in its own goroutine.
+func (t *ThinkTool) sweepExpired(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.pruneExpired()
+	}
+}
+
+// pruneBeforeCalls is a server middleware that removes the thoughts whose TTL
+// has elapsed before every tool call, so that no tool sees an expired thought
+// between two sweeps.
+func (t *ThinkTool) pruneBeforeCalls(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, sess *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if method == "tools/call" {
+			t.pruneExpired()
+		}
+		return next(ctx, sess, method, params)
+	}
+}
+
+// pruneExpired removes the thoughts whose TTL has elapsed. The log is only
+// locked for writing if there are any, so that read-only tools calling it do
+// not block each other.
+func (t *ThinkTool) pruneExpired() {
+	now := t.clock.Now()
+	t.mu.RLock()
+	found := slices.ContainsFunc(t.thoughts, func(item ThoughtItem) bool { return item.expired(now) })
+	t.mu.RUnlock()
+	if !found {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kept := []int{}
+	for i, thought := range t.thoughts {
+		if !thought.expired(now) {
+			kept = append(kept, i)
+		}
+	}
+	if n := len(t.thoughts) - len(kept); n > 0 {
+		t.rearrange(kept)
+		slog.Info("pruned expired thoughts", slog.Int("count", n))
+		t.persist(nil)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fakeClock is a Clock that only moves when the test sets now or calls
+// advance. Its timers only fire in advance, synchronously.
+type fakeClock struct {
+	now    time.Time
+	mu     sync.Mutex // Guards the timers
+	timers []*fakeTimer
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{clock: c, when: c.now.Add(d), f: f, active: true}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// advance moves the clock forward by d and fires the timers that are due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due := []*fakeTimer{}
+	for _, timer := range c.timers {
+		if timer.active && !timer.when.After(c.now) {
+			timer.active = false
+			due = append(due, timer)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	when   time.Time
+	f      func()
+	active bool
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.when, t.active = t.clock.now.Add(d), true
+	return active
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.active = false
+	return active
+}
+
+// connect serves server over an in-memory transport and returns the session
+// of a client connected to it.
+func connect(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ct, st := mcp.NewInMemoryTransports()
+	ss, err := server.Connect(context.Background(), st)
+	if err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil).Connect(context.Background(), ct)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+// callText calls the tool with the given name and arguments and returns the
+// text of its first content.
+func callText(t *testing.T, cs *mcp.ClientSession, name string, args map[string]any) string {
+	t.Helper()
+	res, err := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+	if len(res.Content) == 0 {
+		t.Fatalf("%s returned no content", name)
+	}
+	return res.Content[0].(*mcp.TextContent).Text
+}
+
+func TestExpiredThoughtsArePrunedBeforeCalls(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tool := NewThinkTool(Config{}, clock)
+	server := mcp.NewServer(&mcp.Implementation{Name: serverName}, nil)
+	server.AddReceivingMiddleware(tool.pruneBeforeCalls)
+	addTool(server, &mcp.Tool{Name: "think"}, tool.Think)
+	addTool(server, &mcp.Tool{Name: "get_thoughts_by_day"}, tool.GetThoughtsByDay)
+	addTool(server, &mcp.Tool{Name: "has_thought"}, tool.HasThought)
+	cs := connect(t, server)
+
+	callText(t, cs, "think", map[string]any{"thought": "scratch", "ttl": "30m"})
+	callText(t, cs, "think", map[string]any{"thought": "durable"})
+
+	clock.now = clock.now.Add(29 * time.Minute)
+	if text := callText(t, cs, "has_thought", map[string]any{"text": "scratch"}); !strings.HasPrefix(text, "Yes") {
+		t.Errorf("has_thought before the TTL elapsed = %q, want the thought to exist", text)
+	}
+
+	// The sweeper does not run in the test, so only the middleware can
+	// remove the thought.
+	clock.now = clock.now.Add(time.Minute)
+	if text := callText(t, cs, "has_thought", map[string]any{"text": "scratch"}); !strings.HasPrefix(text, "No") {
+		t.Errorf("has_thought after the TTL elapsed = %q, want the thought to be gone", text)
+	}
+	text := callText(t, cs, "get_thoughts_by_day", map[string]any{})
+	if strings.Contains(text, "scratch") || !strings.Contains(text, "durable") {
+		t.Errorf("get_thoughts_by_day after the TTL elapsed = %q, want only the durable thought", text)
+	}
+}
+
+func TestIdleWarning(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	warnings := func() int { return strings.Count(logs.String(), "no thoughts recorded recently") }
+
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tool := NewThinkTool(Config{}, clock)
+	tool.watchIdle(10 * time.Minute)
+
+	clock.advance(9 * time.Minute)
+	invoke(t, tool.Think, ThinkInput{Thought: "still busy"})
+	clock.advance(9 * time.Minute)
+	if n := warnings(); n != 0 {
+		t.Fatalf("%d warning(s) within 10m of the last thought, want none", n)
+	}
+	clock.advance(time.Minute)
+	if n := warnings(); n != 1 {
+		t.Fatalf("%d warning(s) 10m after the last thought, want 1", n)
+	}
+	clock.advance(10 * time.Minute)
+	if n := warnings(); n != 2 {
+		t.Fatalf("%d warning(s) 20m after the last thought, want the warning to repeat", n)
+	}
+
+	tool.stopIdle()
+	clock.advance(time.Hour)
+	if n := warnings(); n != 2 {
+		t.Errorf("%d warning(s) after stopping the timer, want no more than 2", n)
+	}
+}
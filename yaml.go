@@ -0,0 +1,142 @@
+// Copyright 2025 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// yamlPlain matches the strings that can be written as plain YAML scalars
+// without being mistaken for another type or containing YAML syntax.
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_./-]*$`)
+
+// yamlReserved are the plain scalars YAML 1.1 or 1.2 parsers read as
+// booleans or null.
+var yamlReserved = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "true": true, "false": true,
+	"on": true, "off": true, "null": true,
+}
+
+// yamlString renders s as a YAML scalar at the given indentation. Multi-line
+// printable text becomes a literal block scalar, other text that is not safe
+// to write plain is double-quoted with JSON escapes, which YAML accepts.
+func yamlString(s string, indent int) string {
+	if yamlPlain.MatchString(s) && !yamlReserved[strings.ToLower(s)] {
+		return s
+	}
+	printable := !strings.ContainsFunc(s, func(r rune) bool {
+		return r != '\n' && r != '\t' && !unicode.IsPrint(r)
+	})
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") || !printable {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+
+	header := "|"
+	if s[0] == ' ' || s[0] == '\n' {
+		header += "2" // The indentation cannot be detected from the first line
+	}
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		header += "-"
+	case trailing > 1:
+		header += "+"
+	}
+	pad := strings.Repeat(" ", indent+2)
+	// Clipping and stripping drop the final line breaks, keeping needs
+	// all but the last one written as empty lines.
+	lines := strings.Split(s[:len(body)+max(len(s)-len(body)-1, 0)], "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+// yamlFields renders the fields of a struct as a YAML mapping at the given
+// indentation, using the names of their json tags and skipping the fields
+// that JSON would omit. The first line is not indented so that it can follow
+// a sequence dash.
+func yamlFields(v reflect.Value, indent int) string {
+	pad := strings.Repeat(" ", indent)
+	lines := []string{}
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value := v.Field(i)
+		empty := value.IsZero() || value.Kind() == reflect.Slice && value.Len() == 0
+		if empty && strings.Contains(opts, "omitempty") {
+			continue
+		}
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				lines = append(lines, name+": null")
+				continue
+			}
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.Slice:
+			if value.Len() == 0 {
+				lines = append(lines, name+": []")
+				continue
+			}
+			lines = append(lines, name+":")
+			for j := range value.Len() {
+				lines = append(lines, "  - "+yamlScalar(value.Index(j), indent+4))
+			}
+		default:
+			lines = append(lines, name+": "+yamlScalar(value, indent))
+		}
+	}
+	for i := 1; i < len(lines); i++ {
+		lines[i] = pad + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// yamlScalar renders a string, number or boolean as a YAML scalar.
+func yamlScalar(v reflect.Value, indent int) string {
+	switch v.Kind() {
+	case reflect.String:
+		return yamlString(v.String(), indent)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64, reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	b, _ := json.Marshal(v.Interface())
+	return string(b)
+}
+
+// thoughtsYAML renders thoughts as a YAML sequence of mappings with the same
+// keys as their JSON encoding.
+func thoughtsYAML(thoughts []ThoughtItem) string {
+	if len(thoughts) == 0 {
+		return "[]\n"
+	}
+	var b strings.Builder
+	for _, thought := range thoughts {
+		b.WriteString("- ")
+		b.WriteString(yamlFields(reflect.ValueOf(thought), 2))
+		b.WriteString("\n")
+	}
+	return b.String()
+}